@@ -15,6 +15,7 @@ type Argument struct {
 	SupportsStdin bool // can accept stdin as a value
 	Recursive     bool // supports recursive file adding (with '-r' flag)
 	Description   string
+	Deprecation   *Deprecation // warn or fail if the argument is used
 }
 
 func StringArg(name string, required, variadic bool, description string) Argument {
@@ -46,6 +47,11 @@ func (a Argument) EnableStdin() Argument {
 	return a
 }
 
+func (a Argument) WithDeprecation(d Deprecation) Argument {
+	a.Deprecation = &d
+	return a
+}
+
 func (a Argument) EnableRecursive() Argument {
 	if a.Type != ArgFile {
 		panic("Only FileArgs can enable recursive")
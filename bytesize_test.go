@@ -0,0 +1,31 @@
+package cmds
+
+import "testing"
+
+func TestParseByteSize(t *testing.T) {
+	for _, tc := range []struct {
+		str  string
+		size uint64
+		err  bool
+	}{
+		{str: "0", size: 0},
+		{str: "1024", size: 1024},
+		{str: "256KiB", size: 256 << 10},
+		{str: "256 kib", size: 256 << 10},
+		{str: "1GB", size: 1e9},
+		{str: "1.5MiB", size: 3 << 19},
+		{str: "2k", size: 2000},
+		{str: "16EiB", err: true},
+		{str: "KiB", err: true},
+		{str: "1QB", err: true},
+		{str: "-1", err: true},
+		{str: "", err: true},
+	} {
+		size, err := ParseByteSize(tc.str)
+		if (err != nil) != tc.err {
+			t.Errorf("%q: unexpected error %v", tc.str, err)
+		} else if size != tc.size {
+			t.Errorf("%q: expected %d, got %d", tc.str, tc.size, size)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package cmds
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+	cid "github.com/ipfs/go-cid"
+)
+
+// cidLinkTag is the CBOR tag used for CID links, see
+// https://github.com/ipld/cid-cbor/.
+const cidLinkTag = 42
+
+var (
+	cborEncMode cbor.EncMode
+	cborDecMode cbor.DecMode
+)
+
+func init() {
+	var err error
+
+	// Canonical CBOR sorts map keys length-first, as required by DAG-CBOR.
+	encOpts := cbor.CanonicalEncOptions()
+	encOpts.ShortestFloat = cbor.ShortestFloatNone
+	cborEncMode, err = encOpts.EncMode()
+	if err != nil {
+		panic(err)
+	}
+
+	cborDecMode, err = cbor.DecOptions{
+		DefaultMapType: reflect.TypeFor[map[string]any](),
+	}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+}
+
+// CBOREncoder encodes values as a sequence of CBOR data items (RFC 8742).
+// CIDs are encoded as tag 42 links.
+type CBOREncoder struct {
+	w io.Writer
+}
+
+// NewCBOREncoder returns an encoder writing CBOR to w.
+func NewCBOREncoder(w io.Writer) *CBOREncoder {
+	return &CBOREncoder{w: w}
+}
+
+func (e *CBOREncoder) Encode(v any) error {
+	tree, err := valueTree(v)
+	if err != nil {
+		return err
+	}
+
+	data, err := cborEncMode.Marshal(cborTree(tree))
+	if err != nil {
+		return err
+	}
+
+	_, err = e.w.Write(data)
+	return err
+}
+
+func cborTree(v any) any {
+	switch v := v.(type) {
+	case cid.Cid:
+		// the leading zero is the multibase prefix for raw binary
+		return cbor.Tag{Number: cidLinkTag, Content: append([]byte{0}, v.Bytes()...)}
+	case []any:
+		l := make([]any, len(v))
+		for i := range v {
+			l[i] = cborTree(v[i])
+		}
+		return l
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k := range v {
+			m[k] = cborTree(v[k])
+		}
+		return m
+	default:
+		return v
+	}
+}
+
+// CBORDecoder decodes values from a sequence of CBOR data items.
+type CBORDecoder struct {
+	dec *cbor.Decoder
+}
+
+// NewCBORDecoder returns a decoder reading CBOR from r.
+func NewCBORDecoder(r io.Reader) *CBORDecoder {
+	return &CBORDecoder{dec: cborDecMode.NewDecoder(r)}
+}
+
+func (d *CBORDecoder) Decode(v any) error {
+	var item any
+	if err := d.dec.Decode(&item); err != nil {
+		return err
+	}
+
+	tree, err := treeFromCBOR(item)
+	if err != nil {
+		return err
+	}
+	return unmarshalTree(tree, v)
+}
+
+func treeFromCBOR(v any) (any, error) {
+	switch v := v.(type) {
+	case cbor.Tag:
+		if v.Number != cidLinkTag {
+			return treeFromCBOR(v.Content)
+		}
+
+		b, ok := v.Content.([]byte)
+		if !ok || len(b) == 0 || b[0] != 0 {
+			return nil, fmt.Errorf("invalid CID link")
+		}
+		return cid.Cast(b[1:])
+	case []any:
+		for i := range v {
+			var err error
+			if v[i], err = treeFromCBOR(v[i]); err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+	case map[string]any:
+		for k := range v {
+			var err error
+			if v[k], err = treeFromCBOR(v[k]); err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+	default:
+		return v, nil
+	}
+}
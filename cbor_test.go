@@ -0,0 +1,123 @@
+package cmds
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"reflect"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+type linkTestObj struct {
+	Name  string
+	Link  cid.Cid
+	Data  []byte
+	Skip  string `json:"-"`
+	Empty string `json:",omitempty"`
+	Size  uint64 `json:"size"`
+}
+
+func mustCid(t *testing.T, s string) cid.Cid {
+	c, err := cid.Decode(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCBORLinks(t *testing.T) {
+	c := mustCid(t, "bafkqaaa")
+
+	buf := new(bytes.Buffer)
+	err := NewCBOREncoder(buf).Encode(&linkTestObj{Name: "a", Link: c, Data: []byte{1, 2}, Skip: "x", Size: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// {"Data": h'0102', "Link": 42(h'0001550000'), "Name": "a", "size": 3}
+	exp := "a4" +
+		"6444617461" + "420102" +
+		"644c696e6b" + "d82a" + "45" + "0001550000" +
+		"644e616d65" + "6161" +
+		"6473697a65" + "03"
+	if got := hex.EncodeToString(buf.Bytes()); got != exp {
+		t.Fatalf("expected %s, got %s", exp, got)
+	}
+
+	var v linkTestObj
+	if err := NewCBORDecoder(buf).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, linkTestObj{Name: "a", Link: c, Data: []byte{1, 2}, Size: 3}) {
+		t.Fatalf("unexpected value %#v", v)
+	}
+}
+
+func TestCBORLinkPointers(t *testing.T) {
+	type obj struct {
+		Link  *cid.Cid
+		Empty *cid.Cid
+	}
+	c := mustCid(t, "bafkqaaa")
+
+	buf := new(bytes.Buffer)
+	if err := NewCBOREncoder(buf).Encode(obj{Link: &c}); err != nil {
+		t.Fatal(err)
+	}
+
+	// {"Link": 42(h'0001550000'), "Empty": null}
+	exp := "a2" +
+		"644c696e6b" + "d82a" + "45" + "0001550000" +
+		"65456d707479" + "f6"
+	if got := hex.EncodeToString(buf.Bytes()); got != exp {
+		t.Fatalf("expected %s, got %s", exp, got)
+	}
+
+	var v obj
+	if err := NewCBORDecoder(buf).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Link == nil || !v.Link.Equals(c) || v.Empty != nil {
+		t.Fatalf("unexpected value %#v", v)
+	}
+}
+
+func TestCBORStream(t *testing.T) {
+	buf := new(bytes.Buffer)
+	enc := NewCBOREncoder(buf)
+	for _, v := range []any{&Foo{1}, Foo{2}, &Error{Message: "some error", Code: ErrClient}} {
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dec := NewCBORDecoder(buf)
+	for _, exp := range []*Foo{{1}, {2}} {
+		m := &MaybeError{Value: &Foo{}}
+		if err := dec.Decode(m); err != nil {
+			t.Fatal(err)
+		}
+		v, err := m.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(v, exp) {
+			t.Fatalf("expected %v, got %v", exp, v)
+		}
+	}
+
+	m := &MaybeError{Value: &Foo{}}
+	if err := dec.Decode(m); err != nil {
+		t.Fatal(err)
+	}
+	_, err := m.Get()
+	if e, ok := err.(*Error); !ok || e.Message != "some error" || e.Code != ErrClient {
+		t.Fatalf("expected client error, got %v", err)
+	}
+
+	if err := dec.Decode(&MaybeError{}); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
@@ -53,6 +53,13 @@ type chanStream struct {
 	// length is the length of the response.
 	// It can be set by calling SetLength, but only before the first call to Emit, Close or CloseWithError.
 	length uint64
+
+	// next is the cursor of the next page.
+	// It is written under lock wl before closing, and only read after closeCh is closed.
+	next string
+
+	// progress handles progress reports. It is only accessed by the reader.
+	progress func(Progress)
 }
 
 type chanResponse chanStream
@@ -78,6 +85,15 @@ func (r *chanResponse) Error() *Error {
 	}
 }
 
+func (r *chanResponse) NextCursor() string {
+	select {
+	case <-r.closeCh:
+		return r.next
+	default:
+		return ""
+	}
+}
+
 func (r *chanResponse) Length() uint64 {
 	<-r.waitLen
 
@@ -100,23 +116,33 @@ func (r *chanResponse) Next() (any, error) {
 		return nil, err
 	}
 
-	select {
-	case v, ok := <-r.ch:
-		if !ok {
-			return nil, r.err
-		}
-
-		switch val := v.(type) {
-		case Single:
-			return val.Value, nil
-		default:
-			return v, nil
+	for {
+		select {
+		case v, ok := <-r.ch:
+			if !ok {
+				return nil, r.err
+			}
+
+			switch val := v.(type) {
+			case progressReport:
+				if r.progress != nil {
+					r.progress(val.Progress)
+				}
+			case Single:
+				return val.Value, nil
+			default:
+				return v, nil
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		}
-	case <-ctx.Done():
-		return nil, ctx.Err()
 	}
 }
 
+func (r *chanResponse) SetProgressHandler(f func(Progress)) {
+	r.progress = f
+}
+
 type chanResponseEmitter chanResponse
 
 func (re *chanResponseEmitter) Emit(v any) error {
@@ -181,6 +207,19 @@ func (re *chanResponseEmitter) SetLength(l uint64) {
 	}
 }
 
+func (re *chanResponseEmitter) EmitProgress(p Progress) error {
+	return re.Emit(progressReport{p})
+}
+
+func (re *chanResponseEmitter) SetNextCursor(cursor string) {
+	re.wl.Lock()
+	defer re.wl.Unlock()
+
+	if !re.closed {
+		re.next = cursor
+	}
+}
+
 // SetEncodingType is a no-op for channel emitters.
 // Encoding type only affects HTTP Content-Type headers.
 func (re *chanResponseEmitter) SetEncodingType(encType EncodingType) {}
@@ -49,8 +49,17 @@ func suggestUnknownCmd(args []string, root *cmds.Command) []string {
 		},
 	}
 
+	// suggest the names and aliases of visible commands
+	var names []string
+	for name, sub := range root.Subcommands {
+		if !sub.Hidden {
+			names = append(names, name)
+			names = append(names, sub.Aliases...)
+		}
+	}
+
 	// Start with a simple strings.Contains check
-	for name := range root.Subcommands {
+	for _, name := range names {
 		if strings.Contains(arg, name) {
 			suggestions = append(suggestions, name)
 		}
@@ -61,7 +70,7 @@ func suggestUnknownCmd(args []string, root *cmds.Command) []string {
 		return suggestions
 	}
 
-	for name := range root.Subcommands {
+	for _, name := range names {
 		lev := levenshtein.DistanceForStrings([]rune(arg), []rune(name), options)
 		if lev <= MinLevenshtein {
 			sortableSuggestions = append(sortableSuggestions, &suggestion{name, lev})
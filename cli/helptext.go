@@ -35,6 +35,7 @@ type helpFields struct {
 	Tagline                 string
 	Arguments               string
 	Options                 string
+	Values                  string
 	Synopsis                string
 	Subcommands             string
 	ExperimentalSubcommands string
@@ -60,6 +61,7 @@ func (f *helpFields) TrimNewlines() {
 	f.Tagline = strings.Trim(f.Tagline, "\n")
 	f.Arguments = strings.Trim(f.Arguments, "\n")
 	f.Options = strings.Trim(f.Options, "\n")
+	f.Values = strings.Trim(f.Values, "\n")
 	f.Synopsis = strings.Trim(f.Synopsis, "\n")
 	f.Subcommands = strings.Trim(f.Subcommands, "\n")
 	f.ExperimentalSubcommands = strings.Trim(f.ExperimentalSubcommands, "\n")
@@ -81,6 +83,7 @@ func (f *helpFields) IndentAll() {
 	f.Usage = indent(f.Usage)
 	f.Arguments = indent(f.Arguments)
 	f.Options = indent(f.Options)
+	f.Values = indent(f.Values)
 	f.Synopsis = indent(f.Synopsis)
 	f.Subcommands = indent(f.Subcommands)
 	f.DeprecatedSubcommands = indent(f.DeprecatedSubcommands)
@@ -105,6 +108,10 @@ const longHelpFormat = `{{if .Warning}}WARNING: {{.Warning}}
 
 {{.Options}}
 
+{{end}}{{if .Values}}EFFECTIVE VALUES
+
+{{.Values}}
+
 {{end}}{{if .Description}}DESCRIPTION
 
 {{.Description}}
@@ -185,7 +192,7 @@ func HandleHelp(appName string, req *cmds.Request, out io.Writer) error {
 
 	switch {
 	case long:
-		return LongHelp(appName, req.Root, req.Path, out)
+		return longHelp(appName, req.Root, req.Path, out, req)
 	case short:
 		return ShortHelp(appName, req.Root, req.Path, out)
 	default:
@@ -195,6 +202,12 @@ func HandleHelp(appName string, req *cmds.Request, out io.Writer) error {
 
 // LongHelp writes a formatted CLI helptext string to a Writer for the given command
 func LongHelp(rootName string, root *cmds.Command, path []string, out io.Writer) error {
+	return longHelp(rootName, root, path, out, nil)
+}
+
+// longHelp writes the long help text, listing the effective option values of
+// req and where they came from if req isn't nil.
+func longHelp(rootName string, root *cmds.Command, path []string, out io.Writer, req *cmds.Request) error {
 	cmd, err := root.Get(path)
 	if err != nil {
 		return err
@@ -235,7 +248,14 @@ func LongHelp(rootName string, root *cmds.Command, path []string, out io.Writer)
 		fields.Arguments = strings.Join(argumentText(width, cmd), "\n")
 	}
 	if len(fields.Options) == 0 {
-		fields.Options = strings.Join(optionText(width, cmd), "\n")
+		lines := optionText(width, cmd)
+		if groups := optionGroupText(width, cmd); len(groups) > 0 {
+			lines = append(append(lines, ""), groups...)
+		}
+		fields.Options = strings.Join(lines, "\n")
+	}
+	if req != nil {
+		fields.Values = strings.Join(valueText(req), "\n")
 	}
 	if len(fields.Subcommands) == 0 {
 		fields.Subcommands = strings.Join(subcommandText(width, cmd, rootName, path, cmds.Active), "\n")
@@ -332,25 +352,22 @@ func generateSynopsis(width int, cmd *cmds.Command, path string) string {
 			pre := "-"
 			if len(n) > 1 {
 				pre = "--"
+				if isNegatable(opt) {
+					pre = "--[no-]"
+				}
 			}
-			if opt.Type() == cmds.Bool && opt.Default() == true {
-				pre = "--"
-				sopt = fmt.Sprintf("%s%s=false", pre, n)
-				break
-			} else {
-				if i == 0 {
-					if opt.Type() == cmds.Bool {
-						sopt = fmt.Sprintf("%s%s", pre, n)
-					} else {
-						sopt = fmt.Sprintf("%s%s=<%s>", pre, n, valopt)
-					}
+			if i == 0 {
+				if isFlag(opt) {
+					sopt = fmt.Sprintf("%s%s", pre, n)
 				} else {
-					sopt = fmt.Sprintf("%s | %s%s", sopt, pre, n)
+					sopt = fmt.Sprintf("%s%s=<%s>", pre, n, valopt)
 				}
+			} else {
+				sopt = fmt.Sprintf("%s | %s%s", sopt, pre, n)
 			}
 		}
 
-		if opt.Type() == cmds.Strings {
+		if opt.Type() == cmds.Strings || opt.Type() == cmds.KeyValue {
 			appendText("[" + sopt + "]...")
 		} else {
 			appendText("[" + sopt + "]")
@@ -421,6 +438,12 @@ func optionFlag(flag string) string {
 	return fmt.Sprintf(longFlag, flag)
 }
 
+// isNegatable reports whether help texts should show the --no-<name> form
+// of opt: for tristate flags, and for bool flags that default to true.
+func isNegatable(opt cmds.Option) bool {
+	return opt.Type() == cmds.Tristate || (opt.Type() == cmds.Bool && opt.Default() == true)
+}
+
 func optionText(width int, cmd ...*cmds.Command) []string {
 	// get a slice of the options we want to list out
 	options := make([]cmds.Option, 0)
@@ -434,6 +457,9 @@ func optionText(width int, cmd ...*cmds.Command) []string {
 		flags := sortByLength(opt.Names())
 		for j, f := range flags {
 			flags[j] = optionFlag(f)
+			if isNegatable(opt) && len(f) > 1 {
+				flags[j] = "--[no-]" + f
+			}
 		}
 		lines[i] = strings.Join(flags, ", ")
 	}
@@ -441,7 +467,7 @@ func optionText(width int, cmd ...*cmds.Command) []string {
 
 	// add option types to output
 	for i, opt := range options {
-		lines[i] += "  " + fmt.Sprintf("%v", opt.Type())
+		lines[i] += "  " + cmds.OptionTypeName(opt.Type())
 	}
 	lines = align(lines)
 
@@ -454,6 +480,72 @@ func optionText(width int, cmd ...*cmds.Command) []string {
 	return lines
 }
 
+// optionGroupText describes which options of cmd can be set together.
+func optionGroupText(width int, cmd *cmds.Command) []string {
+	lines := make([]string, 0, len(cmd.OptionGroups))
+	for _, g := range cmd.OptionGroups {
+		flags := make([]string, len(g.Names))
+		for i, name := range g.Names {
+			flags[i] = optionFlag(name)
+		}
+
+		var text string
+		switch g.Kind {
+		case cmds.TogetherGroup:
+			text = fmt.Sprintf("Options %s must be set together.", strings.Join(flags, ", "))
+		default:
+			text = fmt.Sprintf("Options %s are mutually exclusive.", strings.Join(flags, ", "))
+		}
+		lines = append(lines, appendWrapped("", text, width))
+	}
+	return lines
+}
+
+// valueText lists the values of the options set in req and where they came
+// from, e.g. "--timeout  1m0s  (env IPFS_TIMEOUT)".
+func valueText(req *cmds.Request) []string {
+	cmdPath, err := req.Root.Resolve(req.Path)
+	if err != nil {
+		return nil
+	}
+
+	var options []cmds.Option
+	for _, cmd := range cmdPath {
+		for _, opt := range cmd.Options {
+			if opt.Name() == cmds.OptLongHelp || opt.Name() == cmds.OptShortHelp {
+				continue
+			}
+			options = append(options, opt)
+		}
+	}
+
+	var flags, values, sources []string
+	for _, opt := range options {
+		src, ok := req.OptionSource(opt.Name())
+		if !ok {
+			continue
+		}
+		for _, name := range opt.Names() {
+			if v, ok := req.Options[name]; ok {
+				flags = append(flags, optionFlag(opt.Name()))
+				values = append(values, fmt.Sprint(v))
+				sources = append(sources, fmt.Sprintf("(%s)", src))
+				break
+			}
+		}
+	}
+
+	lines := align(flags)
+	for i := range lines {
+		lines[i] += "  " + values[i]
+	}
+	lines = align(lines)
+	for i := range lines {
+		lines[i] += "  " + sources[i]
+	}
+	return lines
+}
+
 func subcommandText(width int, cmd *cmds.Command, rootName string, path []string, status cmds.Status) []string {
 	prefix := fmt.Sprintf("%v %v", rootName, strings.Join(path, " "))
 	if len(path) > 0 {
@@ -464,7 +556,7 @@ func subcommandText(width int, cmd *cmds.Command, rootName string, path []string
 	// Sorting fixes changing order bug #2981.
 	sortedNames := make([]string, 0)
 	for name, c := range cmd.Subcommands {
-		if c.Status == status {
+		if c.Status == status && !c.Hidden {
 			sortedNames = append(sortedNames, name)
 			subCmds[name] = c
 		}
@@ -487,8 +579,17 @@ func subcommandText(width int, cmd *cmds.Command, rootName string, path []string
 
 	lines = align(lines)
 	for i, sub := range subcmds {
+		tagline := sub.Helptext.Tagline
+		switch len(sub.Aliases) {
+		case 0:
+		case 1:
+			tagline += fmt.Sprintf(" (alias: %s)", sub.Aliases[0])
+		default:
+			tagline += fmt.Sprintf(" (aliases: %s)", strings.Join(sub.Aliases, ", "))
+		}
+
 		lines[i] += " - "
-		lines[i] = appendWrapped(lines[i], sub.Helptext.Tagline, width)
+		lines[i] = appendWrapped(lines[i], tagline, width)
 	}
 
 	return lines
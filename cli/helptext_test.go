@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"context"
 	"strings"
 	"testing"
 
@@ -48,3 +49,157 @@ func TestSynopsisGenerator(t *testing.T) {
 		t.Fatal("Synopsis should contain options finalizer")
 	}
 }
+
+func TestOptionConstraintsHelp(t *testing.T) {
+	root := &cmds.Command{
+		Subcommands: map[string]*cmds.Command{
+			"cmd": {
+				Options: []cmds.Option{
+					cmds.WithAllowed(cmds.StringOption("hash", "The hash function."), "sha2-256", "blake3"),
+					cmds.BoolOption("json", "JSON output."),
+					cmds.BoolOption("xml", "XML output."),
+				},
+				OptionGroups: []cmds.OptionGroup{
+					cmds.MutuallyExclusive("json", "xml"),
+				},
+			},
+		},
+	}
+
+	var buf strings.Builder
+	if err := LongHelp("test", root, []string{"cmd"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	help := buf.String()
+	if !strings.Contains(help, "The hash function. Allowed: sha2-256, blake3.") {
+		t.Errorf("help should list the allowed values:\n%s", help)
+	}
+	if !strings.Contains(help, "Options --json, --xml are mutually exclusive.") {
+		t.Errorf("help should describe the option group:\n%s", help)
+	}
+
+	_, err := Parse(context.Background(), []string{"cmd", "--hash=md5"}, nil, root)
+	if e, ok := err.(cmds.Error); !ok || e.Code != cmds.ErrClient {
+		t.Fatalf("expected a client error, got %v", err)
+	}
+}
+
+func TestEffectiveValuesHelp(t *testing.T) {
+	t.Setenv("TEST_TIMEOUT", "1m")
+
+	root := &cmds.Command{
+		Options: []cmds.Option{
+			cmds.BoolOption(cmds.OptLongHelp, cmds.OptShortHelp, "Show the full command help text."),
+			cmds.WithEnv(cmds.DurationOption("timeout", "The timeout."), "TEST_TIMEOUT"),
+			cmds.StringOption("hash", "The hash function.").WithDefault("sha2-256"),
+			cmds.IntOption("count", "The count."),
+		},
+		Subcommands: map[string]*cmds.Command{
+			"cmd": {
+				Options: []cmds.Option{cmds.BoolOption("quiet", "q", "Be quiet.")},
+			},
+		},
+	}
+
+	req, err := Parse(context.Background(), []string{"cmd", "-q", "--help"}, nil, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf strings.Builder
+	if err := HandleHelp("test", req, &buf); err != nil {
+		t.Fatal(err)
+	}
+	help := buf.String()
+	for _, line := range []string{
+		"--timeout  1m0s      (env TEST_TIMEOUT)",
+		"--hash     sha2-256  (default)",
+		"--quiet    true      (flag)",
+	} {
+		if !strings.Contains(help, line) {
+			t.Errorf("help should contain %q:\n%s", line, help)
+		}
+	}
+	if strings.Contains(help, "--count ") || strings.Contains(help, "--help ") {
+		t.Errorf("help should only list set options:\n%s", help)
+	}
+
+	buf.Reset()
+	if err := LongHelp("test", root, nil, &buf); err != nil {
+		t.Fatal(err)
+	}
+	help = buf.String()
+	if !strings.Contains(help, "The timeout. Env: TEST_TIMEOUT.") {
+		t.Errorf("help should list the environment variable:\n%s", help)
+	}
+}
+
+func TestNegatableFlagsHelp(t *testing.T) {
+	cmd := &cmds.Command{
+		Options: []cmds.Option{
+			cmds.BoolOption("pin", "p", "Pin the content.").WithDefault(true),
+			cmds.TristateOption("quiet", "q", "Be quiet."),
+			cmds.BoolOption("verbose", "v", "Be verbose."),
+		},
+	}
+
+	syn := generateSynopsis(100, cmd, "cmd")
+	for _, s := range []string{"[--[no-]pin | -p]", "[--[no-]quiet | -q]", "[--verbose | -v]"} {
+		if !strings.Contains(syn, s) {
+			t.Errorf("synopsis should contain %q: %s", s, syn)
+		}
+	}
+
+	text := strings.Join(optionText(100, cmd), "\n")
+	for _, s := range []string{"-p, --[no-]pin", "-q, --[no-]quiet", "-v, --verbose "} {
+		if !strings.Contains(text, s) {
+			t.Errorf("options should contain %q:\n%s", s, text)
+		}
+	}
+}
+
+func TestAliasesAndHiddenHelp(t *testing.T) {
+	root := &cmds.Command{
+		Subcommands: map[string]*cmds.Command{
+			"ls": {
+				Aliases:  []string{"list"},
+				Helptext: cmds.HelpText{Tagline: "List links."},
+				Run:      func(*cmds.Request, cmds.ResponseEmitter, cmds.Environment) error { return nil },
+			},
+			"debug": {
+				Hidden: true,
+				Run:    func(*cmds.Request, cmds.ResponseEmitter, cmds.Environment) error { return nil },
+			},
+		},
+	}
+
+	var buf strings.Builder
+	if err := ShortHelp("test", root, nil, &buf); err != nil {
+		t.Fatal(err)
+	}
+	help := buf.String()
+	if strings.Count(help, "List links.") != 1 || !strings.Contains(help, "test ls - List links. (alias: list)") {
+		t.Errorf("help should list the command once with its alias:\n%s", help)
+	}
+	if strings.Contains(help, "debug") {
+		t.Errorf("help should not list hidden commands:\n%s", help)
+	}
+
+	req, err := Parse(context.Background(), []string{"list"}, nil, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(req.Path) != 1 || req.Path[0] != "ls" {
+		t.Errorf("expected the canonical path, got %v", req.Path)
+	}
+	if _, err := Parse(context.Background(), []string{"debug"}, nil, root); err != nil {
+		t.Errorf("hidden commands should be callable, got %v", err)
+	}
+
+	if s := suggestUnknownCmd([]string{"lisst"}, root); len(s) != 1 || s[0] != "list" {
+		t.Errorf("expected the alias to be suggested, got %v", s)
+	}
+	if s := suggestUnknownCmd([]string{"debgu"}, root); len(s) != 0 {
+		t.Errorf("hidden commands should not be suggested, got %v", s)
+	}
+}
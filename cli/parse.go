@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"maps"
 	"net/url"
 	"os"
 	"path"
@@ -38,7 +39,11 @@ func Parse(ctx context.Context, input []string, stdin *os.File, root *cmds.Comma
 		return req, err
 	}
 
-	if err := req.FillDefaults(); err != nil {
+	if err := req.FillLocalDefaults(); err != nil {
+		return req, err
+	}
+
+	if err := req.CheckOptions(); err != nil {
 		return req, err
 	}
 
@@ -46,6 +51,10 @@ func Parse(ctx context.Context, input []string, stdin *os.File, root *cmds.Comma
 		return req, err
 	}
 
+	if err := req.CheckDeprecations(); err != nil {
+		return req, err
+	}
+
 	// if no encoding was specified by user, default to plaintext encoding
 	// (if command doesn't support plaintext, use JSON instead)
 	if enc := req.Options[cmds.EncLong]; enc == "" {
@@ -107,6 +116,13 @@ func setOpts(kv kv, kvType reflect.Kind, opts cmds.OptMap) error {
 	if kvType == cmds.Strings {
 		res, _ := opts[kv.Key].([]string)
 		opts[kv.Key] = append(res, kv.Value.([]string)...)
+	} else if kvType == cmds.KeyValue {
+		res, _ := opts[kv.Key].(map[string]string)
+		if res == nil {
+			res = make(map[string]string)
+		}
+		maps.Copy(res, kv.Value.(map[string]string))
+		opts[kv.Key] = res
 	} else if _, exists := opts[kv.Key]; !exists {
 		opts[kv.Key] = kv.Value
 	} else {
@@ -176,10 +192,10 @@ L:
 		default:
 			arg := param
 			// arg is a sub-command or a positional argument
-			sub := cmd.Subcommands[arg]
+			name, sub := cmd.Subcommand(arg)
 			if sub != nil {
 				cmd = sub
-				path = append(path, arg)
+				path = append(path, name)
 				optDefs, err = root.GetOptions(path)
 				if err != nil {
 					return err
@@ -469,11 +485,11 @@ func (st *parseState) parseShortOpts(optDefs map[string]cmds.Option) ([]kv, erro
 			case !ok:
 				return nil, fmt.Errorf("unknown option %q", k)
 
-			case od.Type() == cmds.Bool:
+			case isFlag(od):
 				// single char flags for bools
 				kvs = append(kvs, kv{
 					Key:   od.Name(),
-					Value: true,
+					Value: flagValue(od, true),
 				})
 				j++
 
@@ -511,13 +527,24 @@ func (st *parseState) parseShortOpts(optDefs map[string]cmds.Option) ([]kv, erro
 
 func (st *parseState) parseLongOpt(optDefs map[string]cmds.Option) (string, any, error) {
 	k, v, ok := splitkv(st.peek()[2:])
+
+	// --no-flag disables flags, unless an option is named like that
+	if name, neg := strings.CutPrefix(k, "no-"); neg && optDefs[k] == nil {
+		if optDef, found := optDefs[name]; found && isFlag(optDef) {
+			if ok {
+				return "", nil, fmt.Errorf("option %q does not take a value", k)
+			}
+			return optDef.Name(), flagValue(optDef, false), nil
+		}
+	}
+
 	if !ok {
 		optDef, ok := optDefs[k]
 		if !ok {
 			return "", nil, fmt.Errorf("unknown option %q", k)
 		}
-		if optDef.Type() == cmds.Bool {
-			return k, true, nil
+		if isFlag(optDef) {
+			return k, flagValue(optDef, true), nil
 		}
 		if st.i < len(st.cmdline)-1 {
 			st.i++
@@ -531,6 +558,23 @@ func (st *parseState) parseLongOpt(optDefs map[string]cmds.Option) (string, any,
 	return k, optval, err
 }
 
+// isFlag reports whether opt can be set without a value and negated with
+// --no-<name>.
+func isFlag(opt cmds.Option) bool {
+	return opt.Type() == cmds.Bool || opt.Type() == cmds.Tristate
+}
+
+// flagValue returns the value of the flag opt when enabled or disabled.
+func flagValue(opt cmds.Option, on bool) any {
+	if opt.Type() != cmds.Tristate {
+		return on
+	}
+	if on {
+		return cmds.True
+	}
+	return cmds.False
+}
+
 func getArgDef(i int, argDefs []cmds.Argument) *cmds.Argument {
 	if i < len(argDefs) {
 		// get the argument definition (usually just argDefs[i])
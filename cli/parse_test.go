@@ -7,8 +7,10 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ipfs/boxo/files"
 
@@ -42,6 +44,10 @@ func sameKVs(a kvs, b kvs) bool {
 					return false
 				}
 			}
+		} else if m, ok := v.(map[string]string); ok {
+			if !reflect.DeepEqual(m, b[k]) {
+				return false
+			}
 		} else if v != b[k] {
 			return false
 		}
@@ -102,6 +108,8 @@ func TestOptionParsing(t *testing.T) {
 			cmds.BoolOption("bool", "b", "a bool"),
 			cmds.StringsOption("strings", "r", "strings array"),
 			cmds.DelimitedStringsOption(",", "delimstrings", "d", "comma delimited string array"),
+			cmds.KeyValueOption("header", "H", "key=value pairs"),
+			cmds.DurationOption("timeout", "a duration"),
 		},
 		Subcommands: map[string]*cmds.Command{
 			"test": {},
@@ -161,6 +169,12 @@ func TestOptionParsing(t *testing.T) {
 	test("-d=a,b", kvs{"delimstrings": []string{"a", "b"}}, words{})
 	test("-d=a,b -d c --delimstrings d", kvs{"delimstrings": []string{"a", "b", "c", "d"}}, words{})
 
+	test("-H a=1 --header b=2 -H=a=3", kvs{"header": map[string]string{"a": "3", "b": "2"}}, words{})
+	testFail("--header a")
+	test("--timeout 1m", kvs{"timeout": time.Minute}, words{})
+	testFail("--timeout 1 --timeout 2")
+	testFail("--timeout soon")
+
 	testFail("foo test")
 	test("defaults", kvs{"opt": "def"}, words{})
 	test("defaults -o foo", kvs{"opt": "foo"}, words{})
@@ -866,3 +880,87 @@ func TestFileArgs(t *testing.T) {
 		}
 	}
 }
+
+func TestParseDeprecated(t *testing.T) {
+	root := &cmds.Command{
+		Options: []cmds.Option{
+			cmds.StringOption("cid-base", "The multibase of CIDs."),
+			cmds.WithDeprecation(cmds.StringOption("base", "b", "The multibase of CIDs."), cmds.Deprecation{ReplacedBy: "cid-base"}),
+			cmds.WithDeprecation(cmds.BoolOption("old", "Use the old format."), cmds.Deprecation{Removed: true, RemovedIn: "v0.9.0"}),
+		},
+		Subcommands: map[string]*cmds.Command{
+			"cmd": {
+				Arguments: []cmds.Argument{
+					cmds.StringArg("name", false, false, "The name.").WithDeprecation(cmds.Deprecation{RemovedIn: "v1.0.0"}),
+				},
+			},
+		},
+	}
+
+	req, err := Parse(context.Background(), []string{"cmd", "-b", "base32", "foo"}, nil, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := req.Options["cid-base"]; v != "base32" {
+		t.Errorf("expected the replacement to be set, got %v", v)
+	}
+	exp := []string{
+		`option "base" is deprecated, use "cid-base" instead`,
+		`argument "name" is deprecated and will be removed in v1.0.0`,
+	}
+	if !reflect.DeepEqual(req.Warnings, exp) {
+		t.Errorf("expected warnings %q, got %q", exp, req.Warnings)
+	}
+
+	_, err = Parse(context.Background(), []string{"cmd", "--old"}, nil, root)
+	if e, ok := err.(cmds.Error); !ok || e.Code != cmds.ErrClient || e.Message != `option "old" was removed in v0.9.0` {
+		t.Errorf("expected a client error, got %v", err)
+	}
+}
+
+func TestParseNegation(t *testing.T) {
+	root := &cmds.Command{
+		Options: []cmds.Option{
+			cmds.BoolOption("pin", "p", "Pin the content.").WithDefault(true),
+			cmds.TristateOption("quiet", "q", "Be quiet."),
+			cmds.BoolOption("no-cache", "Skip the cache."),
+			cmds.BoolOption("cache", "Use the cache."),
+			cmds.StringOption("name", "The name."),
+		},
+		Subcommands: map[string]*cmds.Command{"cmd": {}},
+	}
+
+	for _, tc := range []struct {
+		args []string
+		exp  cmds.OptMap
+		err  bool
+	}{
+		{args: []string{"cmd"}, exp: cmds.OptMap{"pin": true}},
+		{args: []string{"cmd", "--no-pin"}, exp: cmds.OptMap{"pin": false}},
+		{args: []string{"cmd", "--no-pin", "-p"}, err: true},
+		{args: []string{"cmd", "-q"}, exp: cmds.OptMap{"pin": true, "quiet": cmds.True}},
+		{args: []string{"cmd", "--quiet"}, exp: cmds.OptMap{"pin": true, "quiet": cmds.True}},
+		{args: []string{"cmd", "--no-quiet"}, exp: cmds.OptMap{"pin": true, "quiet": cmds.False}},
+		{args: []string{"cmd", "--quiet=false"}, exp: cmds.OptMap{"pin": true, "quiet": cmds.False}},
+		// options named no-<flag> take precedence
+		{args: []string{"cmd", "--no-cache"}, exp: cmds.OptMap{"pin": true, "no-cache": true}},
+		{args: []string{"cmd", "--no-pin=true"}, err: true},
+		{args: []string{"cmd", "--no-name"}, err: true},
+	} {
+		req, err := Parse(context.Background(), tc.args, nil, root)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%v: expected an error", tc.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error %s", tc.args, err)
+			continue
+		}
+		delete(req.Options, cmds.EncLong)
+		if !reflect.DeepEqual(req.Options, tc.exp) {
+			t.Errorf("%v: expected %v, got %v", tc.args, tc.exp, req.Options)
+		}
+	}
+}
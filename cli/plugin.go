@@ -0,0 +1,283 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+// PluginDescribeFlag is the only argument passed to a plugin to ask for its
+// PluginSchema. Plugins that support it print the schema as JSON to stdout
+// and exit with status 0.
+const PluginDescribeFlag = "--cmds-plugin-describe"
+
+// Plugin is an executable named <app>-<name> that is run as the subcommand
+// <name> of app, like git runs git-<name> for `git <name>`.
+type Plugin struct {
+	Name string
+	Path string
+}
+
+// PluginSchema describes the help text and the options of a plugin. Plugins
+// with a schema are parsed like other commands, and are passed the options
+// and arguments on their command line.
+type PluginSchema struct {
+	Tagline     string           `json:"tagline"`
+	Description string           `json:"description,omitempty"`
+	Options     []PluginOption   `json:"options,omitempty"`
+	Arguments   []PluginArgument `json:"arguments,omitempty"`
+}
+
+// PluginOption describes an option of a plugin. Type is one of bool, int,
+// uint, int64, uint64, float, string, strings, duration, size, url,
+// multiaddr, cid, key=value and tristate.
+type PluginOption struct {
+	Names       []string `json:"names"`
+	Type        string   `json:"type"`
+	Description string   `json:"description,omitempty"`
+}
+
+// PluginArgument describes a string argument of a plugin.
+type PluginArgument struct {
+	Name        string `json:"name"`
+	Required    bool   `json:"required,omitempty"`
+	Variadic    bool   `json:"variadic,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// pluginKey is the Extra key of the Plugin a command runs.
+type pluginKey struct{}
+
+// FindPlugins returns the plugins of app found in the directories of PATH,
+// sorted by name. If several directories contain a plugin of the same name,
+// the first one wins. Empty and relative directories are skipped.
+func FindPlugins(app string) []Plugin {
+	prefix := app + "-"
+	found := make(map[string]Plugin)
+
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		// like exec.LookPath, never run programs relative to the current
+		// directory
+		if dir == "" || !filepath.IsAbs(dir) {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), prefix)
+			if !ok || entry.IsDir() {
+				continue
+			}
+			name, ok = executableName(name, entry)
+			if !ok || name == "" {
+				continue
+			}
+			if _, ok := found[name]; !ok {
+				found[name] = Plugin{Name: name, Path: filepath.Join(dir, entry.Name())}
+			}
+		}
+	}
+
+	plugins := make([]Plugin, 0, len(found))
+	for _, name := range slices.Sorted(maps.Keys(found)) {
+		plugins = append(plugins, found[name])
+	}
+	return plugins
+}
+
+// executableName strips the executable extension from name on Windows, and
+// reports whether entry is executable.
+func executableName(name string, entry os.DirEntry) (string, bool) {
+	if runtime.GOOS == "windows" {
+		ext := strings.ToLower(filepath.Ext(name))
+		switch ext {
+		case ".exe", ".bat", ".cmd", ".com":
+			return strings.TrimSuffix(name, filepath.Ext(name)), true
+		default:
+			return "", false
+		}
+	}
+
+	info, err := entry.Info()
+	if err != nil {
+		return "", false
+	}
+	return name, info.Mode().IsRegular() && info.Mode()&0o111 != 0
+}
+
+// Describe runs the plugin with PluginDescribeFlag and returns the schema it
+// prints.
+func (p Plugin) Describe(ctx context.Context) (*PluginSchema, error) {
+	out, err := exec.CommandContext(ctx, p.Path, PluginDescribeFlag).Output()
+	if err != nil {
+		return nil, fmt.Errorf("describing plugin %s: %w", p.Name, err)
+	}
+
+	var schema PluginSchema
+	if err := json.Unmarshal(out, &schema); err != nil {
+		return nil, fmt.Errorf("describing plugin %s: %w", p.Name, err)
+	}
+	return &schema, nil
+}
+
+// Command returns the command running the plugin. Without a schema the
+// command is External, and all arguments following it on the command line
+// are passed to the plugin as they are.
+func (p Plugin) Command(schema *PluginSchema) (*cmds.Command, error) {
+	cmd := &cmds.Command{
+		NoRemote: true,
+		Extra:    new(cmds.Extra).SetValue(pluginKey{}, p),
+	}
+
+	if schema == nil {
+		cmd.External = true
+		cmd.Helptext.Tagline = fmt.Sprintf("Run the %s plugin.", p.Name)
+		cmd.Arguments = []cmds.Argument{
+			cmds.StringArg("args", false, true, "Arguments passed to the plugin."),
+		}
+		return cmd, nil
+	}
+
+	cmd.Helptext.Tagline = schema.Tagline
+	cmd.Helptext.ShortDescription = schema.Description
+	for _, o := range schema.Options {
+		kind, ok := cmds.ParseOptionSchemaType(o.Type)
+		if !ok {
+			return nil, fmt.Errorf("plugin %s: option %v has unknown type %q", p.Name, o.Names, o.Type)
+		}
+		if len(o.Names) == 0 {
+			return nil, fmt.Errorf("plugin %s: option without a name", p.Name)
+		}
+		cmd.Options = append(cmd.Options, cmds.NewOption(kind, append(slices.Clone(o.Names), o.Description)...))
+	}
+	for _, a := range schema.Arguments {
+		cmd.Arguments = append(cmd.Arguments, cmds.StringArg(a.Name, a.Required, a.Variadic, a.Description))
+	}
+	return cmd, nil
+}
+
+// MountPlugins adds the plugins of app found in PATH to the subcommands of
+// root. Plugins never replace built-in commands. If describe is set, every
+// plugin is asked for its schema, and plugins that fail to describe
+// themselves are mounted as External commands. As plugins that don't know
+// PluginDescribeFlag may treat it as a regular argument, only set describe
+// if all plugins of app are expected to support it.
+func MountPlugins(ctx context.Context, root *cmds.Command, app string, describe bool) error {
+	for _, p := range FindPlugins(app) {
+		if name, _ := root.Subcommand(p.Name); name != "" {
+			continue
+		}
+
+		var schema *PluginSchema
+		if describe {
+			var err error
+			schema, err = p.Describe(ctx)
+			if err != nil {
+				log.Debugf("mounting plugin %s without a schema: %s", p.Name, err)
+			}
+		}
+
+		cmd, err := p.Command(schema)
+		if err != nil {
+			return err
+		}
+		if root.Subcommands == nil {
+			root.Subcommands = make(map[string]*cmds.Command)
+		}
+		root.Subcommands[p.Name] = cmd
+	}
+	return nil
+}
+
+// pluginOf returns the plugin run by cmd, if any.
+func pluginOf(cmd *cmds.Command) (Plugin, bool) {
+	if cmd == nil {
+		return Plugin{}, false
+	}
+	v, ok := cmd.Extra.GetValue(pluginKey{})
+	if !ok {
+		return Plugin{}, false
+	}
+	p, ok := v.(Plugin)
+	return p, ok
+}
+
+// runPlugin runs the plugin of req with the standard streams of the CLI.
+// The exit status of the plugin is returned as an ExitError.
+func runPlugin(req *cmds.Request, p Plugin, stdin, stdout, stderr *os.File) error {
+	c := exec.CommandContext(req.Context, p.Path, pluginArgs(req)...)
+	// a nil *os.File would be passed on as a closed stdin
+	if stdin != nil {
+		c.Stdin = stdin
+	}
+	c.Stdout = stdout
+	c.Stderr = stderr
+
+	err := c.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		return ExitError(exitErr.ExitCode())
+	}
+	return err
+}
+
+// pluginArgs returns the command line of the plugin: the arguments of
+// External plugins, or the options of the plugin that aren't defaults
+// followed by its arguments.
+func pluginArgs(req *cmds.Request) []string {
+	if req.Command.External {
+		return req.Arguments
+	}
+
+	var args []string
+	for _, opt := range req.Command.Options {
+		if src, ok := req.OptionSource(opt.Name()); ok && src.Kind == cmds.SourceDefault {
+			continue
+		}
+
+		var v any
+		for _, name := range opt.Names() {
+			if val, ok := req.Options[name]; ok {
+				v = val
+				break
+			}
+		}
+
+		flag := "--" + opt.Name()
+		switch v := v.(type) {
+		case nil:
+		case []string:
+			for _, s := range v {
+				args = append(args, flag+"="+s)
+			}
+		case map[string]string:
+			for _, k := range slices.Sorted(maps.Keys(v)) {
+				args = append(args, flag+"="+k+"="+v[k])
+			}
+		case cmds.TriBool:
+			if v.IsSet() {
+				args = append(args, flag+"="+v.String())
+			}
+		default:
+			args = append(args, fmt.Sprintf("%s=%v", flag, v))
+		}
+	}
+
+	if slices.ContainsFunc(req.Arguments, func(arg string) bool { return strings.HasPrefix(arg, "-") }) {
+		args = append(args, "--")
+	}
+	return append(args, req.Arguments...)
+}
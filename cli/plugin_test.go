@@ -0,0 +1,147 @@
+//go:build !windows
+
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+const echoPlugin = `#!/bin/sh
+echo "$@"
+exit 3
+`
+
+const describedPlugin = `#!/bin/sh
+if [ "$1" = "--cmds-plugin-describe" ]; then
+	echo '{"tagline": "Greet people.", "options": [{"names": ["loud", "l"], "type": "bool"}, {"names": ["greeting"], "type": "string"}], "arguments": [{"name": "name", "required": true, "variadic": true}]}'
+	exit 0
+fi
+echo "$@"
+`
+
+const stdinPlugin = `#!/bin/sh
+exec 3<&0 || exit 1
+echo open
+`
+
+func writePlugin(t *testing.T, dir, name, script string, mode os.FileMode) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(script), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindPlugins(t *testing.T) {
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	writePlugin(t, dir1, "app-echo", echoPlugin, 0o755)
+	writePlugin(t, dir1, "app-notexec", echoPlugin, 0o644)
+	writePlugin(t, dir2, "app-echo", echoPlugin, 0o755)
+	writePlugin(t, dir2, "app-greet", describedPlugin, 0o755)
+	writePlugin(t, dir2, "other-echo", echoPlugin, 0o755)
+	t.Setenv("PATH", dir1+string(os.PathListSeparator)+dir2)
+
+	exp := []Plugin{
+		{Name: "echo", Path: filepath.Join(dir1, "app-echo")},
+		{Name: "greet", Path: filepath.Join(dir2, "app-greet")},
+	}
+	if plugins := FindPlugins("app"); !reflect.DeepEqual(plugins, exp) {
+		t.Fatalf("expected plugins %v, got %v", exp, plugins)
+	}
+
+	// the current directory is never searched
+	cwd := t.TempDir()
+	writePlugin(t, cwd, "app-local", echoPlugin, 0o755)
+	t.Chdir(cwd)
+	sep := string(os.PathListSeparator)
+	t.Setenv("PATH", sep+dir2+sep+".")
+	exp = []Plugin{
+		{Name: "echo", Path: filepath.Join(dir2, "app-echo")},
+		{Name: "greet", Path: filepath.Join(dir2, "app-greet")},
+	}
+	if plugins := FindPlugins("app"); !reflect.DeepEqual(plugins, exp) {
+		t.Fatalf("expected plugins %v, got %v", exp, plugins)
+	}
+}
+
+func TestRunPlugins(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "app-echo", echoPlugin, 0o755)
+	writePlugin(t, dir, "app-greet", describedPlugin, 0o755)
+	writePlugin(t, dir, "app-version", echoPlugin, 0o755)
+	writePlugin(t, dir, "app-stdin", stdinPlugin, 0o755)
+	t.Setenv("PATH", dir)
+
+	root := &cmds.Command{
+		Options: []cmds.Option{
+			cmds.BoolOption(cmds.OptLongHelp, cmds.OptShortHelp, "Show the full command help text."),
+		},
+		Subcommands: map[string]*cmds.Command{
+			"version": {Run: func(*cmds.Request, cmds.ResponseEmitter, cmds.Environment) error { return nil }},
+		},
+	}
+	builtin := root.Subcommands["version"]
+	if err := MountPlugins(context.Background(), root, "app", true); err != nil {
+		t.Fatal(err)
+	}
+	if root.Subcommands["version"] != builtin {
+		t.Fatal("plugins should not replace built-in commands")
+	}
+	if !root.Subcommands["echo"].External || root.Subcommands["greet"].External {
+		t.Fatal("only plugins without a schema should be external")
+	}
+	if tagline := root.Subcommands["greet"].Helptext.Tagline; tagline != "Greet people." {
+		t.Fatalf("unexpected tagline %q", tagline)
+	}
+
+	run := func(args ...string) (string, error) {
+		out, err := os.Create(filepath.Join(t.TempDir(), "out"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer out.Close()
+
+		err = Run(context.Background(), root, append([]string{"app"}, args...), nil, out, out,
+			func(context.Context, *cmds.Request) (cmds.Environment, error) { return nil, nil },
+			func(req *cmds.Request, env any) (cmds.Executor, error) { return cmds.NewExecutor(req.Root), nil },
+		)
+		data, _ := os.ReadFile(out.Name())
+		return string(data), err
+	}
+
+	out, err := run("echo", "--flag", "a")
+	if err != ExitError(3) {
+		t.Errorf("expected the exit status of the plugin, got %v", err)
+	}
+	if out != "--flag a\n" {
+		t.Errorf("expected the arguments to be passed through, got %q", out)
+	}
+
+	// without a stdin, the plugin still gets an open one
+	out, err = run("stdin")
+	if err != nil || out != "open\n" {
+		t.Errorf("expected an open stdin, got %q, %v", out, err)
+	}
+
+	out, err = run("greet", "-l", "--greeting=hi", "--", "alice", "-bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "--loud=true --greeting=hi -- alice -bob\n" {
+		t.Errorf("unexpected plugin command line %q", out)
+	}
+
+	out, err = run("greet", "--help")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "app greet [--loud | -l] [--greeting=<greeting>] [--] <name>...") {
+		t.Errorf("expected help generated from the schema, got:\n%s", out)
+	}
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+// barWidth is the width of the bar itself, without the labels around it.
+const barWidth = 30
+
+// progressWidth returns the width of the terminal w writes to, or 0 if it
+// is not a terminal.
+func progressWidth(w io.Writer) int {
+	if !IsTerminal(w) {
+		return 0
+	}
+	return getTerminalWidth(w)
+}
+
+// progressBar renders p on a single line of at most width characters, e.g.
+//
+//	adding [=========>          ]  45% 450/1000 some/file
+//
+// If the total is unknown, only the amount of work done is shown.
+func progressBar(p cmds.Progress, width int) string {
+	var parts []string
+	if p.Phase != "" {
+		parts = append(parts, p.Phase)
+	}
+
+	if p.Total > 0 {
+		done := min(p.Done, p.Total)
+		filled := int(done * barWidth / p.Total)
+
+		bar := strings.Repeat("=", filled)
+		if filled < barWidth {
+			bar += ">" + strings.Repeat(" ", barWidth-filled-1)
+		}
+		parts = append(parts, fmt.Sprintf("[%s] %3d%% %d/%d", bar, done*100/p.Total, p.Done, p.Total))
+	} else {
+		parts = append(parts, fmt.Sprint(p.Done))
+	}
+
+	if p.Message != "" {
+		parts = append(parts, p.Message)
+	}
+
+	return truncateLine(strings.Join(parts, " "), width)
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+func TestProgressBar(t *testing.T) {
+	testCases := []struct {
+		p     cmds.Progress
+		width int
+		exp   string
+	}{
+		{cmds.Progress{Done: 0, Total: 10}, 80, "[>                             ]   0% 0/10"},
+		{cmds.Progress{Done: 5, Total: 10, Phase: "adding", Message: "file"}, 80, "adding [===============>              ]  50% 5/10 file"},
+		{cmds.Progress{Done: 12, Total: 10}, 80, "[==============================] 100% 12/10"},
+		{cmds.Progress{Done: 42, Message: "blocks"}, 80, "42 blocks"},
+		{cmds.Progress{Done: 42, Phase: "fetching", Message: "some long message"}, 12, "fetching 42"},
+	}
+
+	for _, tc := range testCases {
+		if got := progressBar(tc.p, tc.width); got != tc.exp {
+			t.Errorf("expected %q, got %q", tc.exp, got)
+		}
+	}
+}
+
+func TestEmitProgress(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	req := &cmds.Request{Command: &cmds.Command{}, Options: cmds.OptMap{cmds.EncLong: cmds.Text}}
+
+	re, err := NewResponseEmitter(&stdout, &stderr, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// stderr is not a terminal, so progress is not shown
+	if err := cmds.EmitProgress(re, cmds.Progress{Done: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if stderr.Len() != 0 {
+		t.Fatalf("expected no progress on a non-terminal, got %q", stderr.String())
+	}
+
+	re.(*responseEmitter).progressWidth = 40
+	if err := cmds.EmitProgress(re, cmds.Progress{Done: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := re.Emit("value\n"); err != nil {
+		t.Fatal(err)
+	}
+	if exp := "\r1\033[K\r\033[K"; stderr.String() != exp {
+		t.Fatalf("expected the progress bar to be cleared before output, got %q", stderr.String())
+	}
+	if stdout.String() != "value\n" {
+		t.Fatalf("unexpected output %q", stdout.String())
+	}
+}
@@ -10,7 +10,10 @@ import (
 	cmds "github.com/ipfs/go-ipfs-cmds"
 )
 
-var _ ResponseEmitter = &responseEmitter{}
+var (
+	_ ResponseEmitter = &responseEmitter{}
+	_ LineUpdater     = &responseEmitter{}
+)
 
 // NewResponseEmitter constructs a new response emitter that writes results to
 // the console.
@@ -18,10 +21,12 @@ func NewResponseEmitter(stdout, stderr io.Writer, req *cmds.Request) (ResponseEm
 	encType, enc, err := cmds.GetEncoder(req, stdout, cmds.TextNewline)
 
 	return &responseEmitter{
-		stdout:  stdout,
-		stderr:  stderr,
-		encType: encType,
-		enc:     enc,
+		stdout:        stdout,
+		stderr:        stderr,
+		encType:       encType,
+		enc:           enc,
+		stdoutTTY:     IsTerminal(stdout),
+		progressWidth: progressWidth(stderr),
 	}, err
 }
 
@@ -39,6 +44,19 @@ type ResponseEmitter interface {
 	Status() int
 }
 
+// LineUpdater is implemented by response emitters that can show a status
+// line, like the ones returned by NewResponseEmitter.
+type LineUpdater interface {
+	// UpdateLine replaces the status line on stdout with line, e.g. to show
+	// the progress of a PostRun function. If stdout is not a terminal, only
+	// the last line is written, once it is finished.
+	UpdateLine(line string)
+
+	// FinishLine ends the status line, keeping its text. Other output ends
+	// the status line as well, but removes it first.
+	FinishLine()
+}
+
 type responseEmitter struct {
 	l      sync.Mutex
 	stdout io.Writer
@@ -49,6 +67,16 @@ type responseEmitter struct {
 	encType cmds.EncodingType
 	exit    int
 	closed  bool
+
+	// stdoutTTY is set if stdout is a terminal, so the status line can be
+	// updated in place.
+	stdoutTTY bool
+	line      string
+
+	// progressWidth is the width of progress bars, or 0 if stderr is not a
+	// terminal and progress is not shown.
+	progressWidth int
+	progressShown bool
 }
 
 func (re *responseEmitter) Type() cmds.PostRunType {
@@ -63,17 +91,18 @@ func (re *responseEmitter) SetEncodingType(encType cmds.EncodingType) {
 	re.encType = encType
 }
 
-// SetContentType is a no-op for CLI emitters.
-// Content-Type only affects HTTP headers.
-func (re *responseEmitter) SetContentType(contentType string) {}
-
-func (re *responseEmitter) isClosed() bool {
+// SetNextCursor tells the user how to get the next page.
+func (re *responseEmitter) SetNextCursor(cursor string) {
 	re.l.Lock()
 	defer re.l.Unlock()
 
-	return re.closed
+	fmt.Fprintf(re.stderr, "More results available, continue with --%s=%s\n", cmds.CursorOpt, cursor)
 }
 
+// SetContentType is a no-op for CLI emitters.
+// Content-Type only affects HTTP headers.
+func (re *responseEmitter) SetContentType(contentType string) {}
+
 func (re *responseEmitter) Close() error {
 	return re.CloseWithError(nil)
 }
@@ -86,6 +115,8 @@ func (re *responseEmitter) CloseWithError(err error) error {
 		return cmds.ErrClosingClosedEmitter
 	}
 	re.closed = true
+	re.clearProgress()
+	re.finishLine()
 
 	var msg string
 	if err != nil {
@@ -154,7 +185,13 @@ func (re *responseEmitter) Emit(v any) error {
 		v = *c
 	}
 
-	if re.isClosed() {
+	re.l.Lock()
+	closed := re.closed
+	re.clearProgress()
+	re.clearLine()
+	re.l.Unlock()
+
+	if closed {
 		return cmds.ErrClosedEmitter
 	}
 
@@ -181,6 +218,81 @@ func (re *responseEmitter) Emit(v any) error {
 	return err
 }
 
+// EmitProgress renders a progress bar on stderr, if it is a terminal. The
+// bar is updated in place and removed before any other output.
+func (re *responseEmitter) EmitProgress(p cmds.Progress) error {
+	re.l.Lock()
+	defer re.l.Unlock()
+
+	if re.closed {
+		return cmds.ErrClosedEmitter
+	}
+	if re.progressWidth == 0 {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(re.stderr, "\r%s\033[K", progressBar(p, re.progressWidth))
+	re.progressShown = true
+	return err
+}
+
+func (re *responseEmitter) UpdateLine(line string) {
+	re.l.Lock()
+	defer re.l.Unlock()
+
+	if re.closed {
+		return
+	}
+
+	re.clearProgress()
+	re.line = line
+	if re.stdoutTTY {
+		fmt.Fprintf(re.stdout, "\r%s\033[K", truncateLine(line, getTerminalWidth(re.stdout)))
+	}
+}
+
+func (re *responseEmitter) FinishLine() {
+	re.l.Lock()
+	defer re.l.Unlock()
+
+	re.finishLine()
+}
+
+// finishLine must be called with the lock held.
+func (re *responseEmitter) finishLine() {
+	if re.line == "" {
+		return
+	}
+
+	if re.stdoutTTY {
+		fmt.Fprintln(re.stdout)
+	} else {
+		fmt.Fprintln(re.stdout, re.line)
+	}
+	re.line = ""
+}
+
+// clearLine removes the status line. It must be called with the lock held.
+func (re *responseEmitter) clearLine() {
+	if re.line == "" {
+		return
+	}
+
+	if re.stdoutTTY {
+		fmt.Fprint(re.stdout, "\r\033[K")
+	}
+	re.line = ""
+}
+
+// clearProgress removes the progress bar, so that other output doesn't get
+// mixed up with it. It must be called with the lock held.
+func (re *responseEmitter) clearProgress() {
+	if re.progressShown {
+		fmt.Fprint(re.stderr, "\r\033[K")
+		re.progressShown = false
+	}
+}
+
 // Stderr returns the ResponseWriter's stderr
 func (re *responseEmitter) Stderr() io.Writer {
 	return re.stderr
@@ -6,7 +6,6 @@ import (
 	"io"
 	"os"
 	"strings"
-	"time"
 
 	cmds "github.com/ipfs/go-ipfs-cmds"
 )
@@ -35,12 +34,10 @@ func Run(ctx context.Context, root *cmds.Command,
 
 	// Handle the timeout up front.
 	var cancel func()
-	if timeoutStr, ok := req.Options[cmds.TimeoutOpt]; ok {
-		timeout, err := time.ParseDuration(timeoutStr.(string))
-		if err != nil {
-			printErr(err)
-			return err
-		}
+	if timeout, ok, err := req.Timeout(); err != nil {
+		printErr(err)
+		return err
+	} else if ok {
 		req.Context, cancel = context.WithTimeout(req.Context, timeout)
 	} else {
 		req.Context, cancel = context.WithCancel(req.Context)
@@ -95,6 +92,19 @@ func Run(ctx context.Context, root *cmds.Command,
 		return errParse
 	}
 
+	for _, w := range req.Warnings {
+		fmt.Fprintf(stderr, "Warning: %s\n", w)
+	}
+
+	// plugins run in the foreground with the standard streams of the CLI
+	if p, ok := pluginOf(req.Command); ok {
+		err := runPlugin(req, p, stdin, stdout, stderr)
+		if _, isExit := err.(ExitError); err != nil && !isExit {
+			printErr(err)
+		}
+		return err
+	}
+
 	// here we handle the cases where
 	// - commands with no Run func are invoked directly.
 	// - the main command is invoked.
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf8"
+
+	terminal "golang.org/x/term"
+)
+
+// Color is an ANSI text color or style.
+type Color int
+
+// Bold is the bold text style.
+const Bold Color = 1
+
+// Colors for Colorize.
+const (
+	Red Color = iota + 31
+	Green
+	Yellow
+	Blue
+	Magenta
+	Cyan
+)
+
+// IsTerminal reports whether w is a terminal. Output to a terminal can be
+// interactive, while output to pipes and files should be plain.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && terminal.IsTerminal(int(f.Fd()))
+}
+
+// TerminalWidth returns the width of the terminal w writes to, or a default
+// width of 80 columns if it is not a terminal.
+func TerminalWidth(w io.Writer) int {
+	return getTerminalWidth(w)
+}
+
+// Colorize returns s in the color c, if w is a terminal supporting colors.
+// Colors can be disabled by setting the NO_COLOR environment variable.
+func Colorize(w io.Writer, c Color, s string) string {
+	if !colorEnabled(w) {
+		return s
+	}
+	return fmt.Sprintf("\033[%dm%s\033[0m", c, s)
+}
+
+func colorEnabled(w io.Writer) bool {
+	return IsTerminal(w) && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+}
+
+// truncateLine shortens s so that it fits on a line of the given width. The
+// last column is left free, as some terminals wrap when it is written to,
+// which breaks updating the line in place.
+func truncateLine(s string, width int) string {
+	if utf8.RuneCountInString(s) >= width {
+		return string([]rune(s)[:max(width-1, 0)])
+	}
+	return s
+}
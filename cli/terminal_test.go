@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+func TestTerminalHelpers(t *testing.T) {
+	var buf bytes.Buffer
+	if IsTerminal(&buf) {
+		t.Fatal("a buffer is not a terminal")
+	}
+	if w := TerminalWidth(&buf); w != defaultTerminalWidth {
+		t.Fatalf("expected default width, got %d", w)
+	}
+	if s := Colorize(&buf, Red, "plain"); s != "plain" {
+		t.Fatalf("expected no colors when piped, got %q", s)
+	}
+
+	for _, tc := range []struct {
+		s     string
+		width int
+		exp   string
+	}{
+		{"short", 80, "short"},
+		{"exactly", 7, "exactl"},
+		{"héllo wörld", 6, "héllo"},
+	} {
+		if got := truncateLine(tc.s, tc.width); got != tc.exp {
+			t.Errorf("expected %q, got %q", tc.exp, got)
+		}
+	}
+}
+
+func TestUpdateLine(t *testing.T) {
+	testCases := []struct {
+		tty bool
+		exp string
+	}{
+		{tty: true, exp: "\r1 left\033[K\r0 left\033[K\r\033[Kvalue\n\rdone\033[K\n"},
+		// piped output only gets finished lines
+		{tty: false, exp: "value\ndone\n"},
+	}
+
+	for _, tc := range testCases {
+		var stdout, stderr bytes.Buffer
+		req := &cmds.Request{Command: &cmds.Command{}, Options: cmds.OptMap{cmds.EncLong: cmds.Text}}
+
+		re, err := NewResponseEmitter(&stdout, &stderr, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		re.(*responseEmitter).stdoutTTY = tc.tty
+		lines := re.(LineUpdater)
+
+		lines.UpdateLine("1 left")
+		lines.UpdateLine("0 left")
+		// other output removes the status line
+		if err := re.Emit("value\n"); err != nil {
+			t.Fatal(err)
+		}
+		lines.UpdateLine("done")
+		// closing finishes the line
+		if err := re.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if stdout.String() != tc.exp {
+			t.Errorf("tty=%t: expected %q, got %q", tc.tty, tc.exp, stdout.String())
+		}
+	}
+}
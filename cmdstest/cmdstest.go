@@ -0,0 +1,243 @@
+// Package cmdstest runs commands in tests the way the command line would,
+// and captures what they emit.
+//
+//	h := cmdstest.New(root, env)
+//	res := h.Run(t, "add", "1", "2", "--enc=json")
+//	if res.Err != nil {
+//		t.Fatal(res.Err)
+//	}
+//	cmdstest.Golden(t, "add.json", res.Output(t))
+//
+// Golden files are read from the testdata directory of the package under
+// test. Run the tests with -cmdstest.update to write them instead.
+package cmdstest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/ipfs/go-ipfs-cmds/cli"
+)
+
+var update = flag.Bool("cmdstest.update", false, "write golden files instead of comparing against them")
+
+// Harness runs the commands of a root command.
+type Harness struct {
+	// Root is the root command that arguments are parsed against.
+	Root *cmds.Command
+
+	// Env is the environment commands are run with.
+	Env cmds.Environment
+
+	// Name is the name of the program in help output.
+	Name string
+}
+
+// New returns a Harness that runs the commands of root with env.
+func New(root *cmds.Command, env cmds.Environment) *Harness {
+	return &Harness{
+		Root: root,
+		Env:  env,
+		Name: "cmd",
+	}
+}
+
+// Result is the outcome of running a command.
+type Result struct {
+	// Request is the parsed request. It may be nil or incomplete if argv
+	// could not be parsed.
+	Request *cmds.Request
+
+	// Values are the values emitted by the command, in order. Values
+	// emitted as io.Readers are read completely and captured as []byte.
+	Values []any
+
+	// Progress are the progress reports of the command, in order.
+	Progress []cmds.Progress
+
+	// Length is the length the command set, if any.
+	Length uint64
+
+	// NextCursor is the cursor of the next page, if any.
+	NextCursor string
+
+	// Err is the error parsing or running the command failed with.
+	Err error
+
+	// Status is the exit status the command line would exit with.
+	Status int
+
+	// Help is the help text, if --help or -h was passed.
+	Help []byte
+}
+
+// Run parses argv, without the program name, as the command line would and
+// runs the resulting request. Unlike the command line, it uses the chan
+// response pair, so PostRun functions for the command line are not run.
+func (h *Harness) Run(t testing.TB, argv ...string) *Result {
+	t.Helper()
+
+	res := &Result{}
+
+	req, err := cli.Parse(context.Background(), argv, nil, h.Root)
+	res.Request = req
+
+	if req != nil {
+		var help bytes.Buffer
+		switch err := cli.HandleHelp(h.Name, req, &help); err {
+		case nil:
+			res.Help = help.Bytes()
+			return res
+		case cli.ErrNoHelpRequested:
+		default:
+			t.Fatal(err)
+		}
+	}
+
+	if err != nil {
+		res.fail(err)
+		return res
+	}
+	if req.Command.Run == nil {
+		res.fail(cmds.ErrNotCallable)
+		return res
+	}
+
+	re, cres := cmds.NewChanResponsePair(req)
+	cres.(cmds.ProgressResponse).SetProgressHandler(func(p cmds.Progress) {
+		res.Progress = append(res.Progress, p)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// Execute returns the errors that happen before the command is
+		// run without closing the emitter.
+		if err := cmds.NewExecutor(h.Root).Execute(req, re, h.Env); err != nil {
+			re.CloseWithError(err)
+		}
+	}()
+
+	for {
+		v, err := cres.Next()
+		if err != nil {
+			if err != io.EOF {
+				res.fail(err)
+			}
+			break
+		}
+
+		if r, ok := v.(io.Reader); ok {
+			data, err := io.ReadAll(r)
+			if err != nil {
+				res.fail(err)
+				break
+			}
+			v = data
+		}
+		res.Values = append(res.Values, v)
+	}
+
+	<-done
+
+	res.Length = cres.Length()
+	res.NextCursor = cres.(cmds.CursorResponse).NextCursor()
+	return res
+}
+
+// Help returns the long help text of the command at path.
+func (h *Harness) Help(t testing.TB, path ...string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := cli.LongHelp(h.Name, h.Root, path, &buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func (res *Result) fail(err error) {
+	res.Err = err
+
+	var exitErr cli.ExitError
+	if errors.As(err, &exitErr) {
+		res.Status = int(exitErr)
+	} else {
+		res.Status = 1
+	}
+}
+
+// Output returns what the command line would print on stdout: the help
+// text if it was requested, or else the values encoded with the requested
+// encoding.
+func (res *Result) Output(t testing.TB) []byte {
+	t.Helper()
+
+	if res.Help != nil {
+		return res.Help
+	}
+	if res.Request == nil || res.Request.Command == nil {
+		t.Fatal("cmdstest: no command to encode the values of")
+	}
+
+	// like the command line, use JSON if the command has no text encoder
+	req := res.Request
+	encType := cmds.GetEncoding(req, cmds.Text)
+	if _, ok := req.Command.Encoders[encType]; encType == cmds.Text && !ok {
+		r := *req
+		r.Options = maps.Clone(req.Options)
+		r.Options[cmds.EncLong] = cmds.JSON
+		req = &r
+	}
+
+	var buf bytes.Buffer
+	_, enc, err := cmds.GetEncoder(req, &buf, cmds.Text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range res.Values {
+		if data, ok := v.([]byte); ok {
+			buf.Write(data)
+			continue
+		}
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+// Golden compares got with the contents of the golden file testdata/name,
+// and fails t if they differ. With -cmdstest.update, it writes got to the
+// file instead.
+func Golden(t testing.TB, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file: %s", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s:\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
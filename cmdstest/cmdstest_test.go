@@ -0,0 +1,128 @@
+package cmdstest
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/ipfs/go-ipfs-cmds/cli"
+)
+
+type greeting struct {
+	Name     string
+	Greeting string
+}
+
+var root = &cmds.Command{
+	Options: []cmds.Option{
+		cmds.OptionEncodingType,
+		cmds.BoolOption(cmds.OptLongHelp, cmds.OptShortHelp, "Show the full command help text."),
+	},
+	Subcommands: map[string]*cmds.Command{
+		"greet": {
+			Helptext: cmds.HelpText{
+				Tagline: "Greet people.",
+			},
+			Arguments: []cmds.Argument{
+				cmds.StringArg("name", true, true, "The names of the people to greet."),
+			},
+			Options: []cmds.Option{
+				cmds.StringOption("greeting", "g", "The greeting to use.").WithDefault("hello"),
+			},
+			Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+				re.SetLength(uint64(len(req.Arguments)))
+				for i, name := range req.Arguments {
+					if err := cmds.EmitProgress(re, cmds.Progress{Done: uint64(i), Total: uint64(len(req.Arguments))}); err != nil {
+						return err
+					}
+					if err := re.Emit(&greeting{Name: name, Greeting: req.Options["greeting"].(string)}); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			Type: greeting{},
+			Encoders: cmds.EncoderMap{
+				cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, g *greeting) error {
+					_, err := fmt.Fprintf(w, "%s, %s!\n", g.Greeting, g.Name)
+					return err
+				}),
+			},
+		},
+		"fail": {
+			Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+				return cli.ExitError(3)
+			},
+		},
+		"env": {
+			Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+				return cmds.EmitOnce(re, env)
+			},
+		},
+	},
+}
+
+func TestRun(t *testing.T) {
+	h := New(root, "test env")
+
+	res := h.Run(t, "greet", "alice", "bob", "-g", "hi")
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	want := []any{
+		&greeting{Name: "alice", Greeting: "hi"},
+		&greeting{Name: "bob", Greeting: "hi"},
+	}
+	if !reflect.DeepEqual(res.Values, want) {
+		t.Fatalf("unexpected values %v", res.Values)
+	}
+	if res.Length != 2 {
+		t.Errorf("expected length 2, got %d", res.Length)
+	}
+	if len(res.Progress) != 2 {
+		t.Errorf("expected 2 progress reports, got %d", len(res.Progress))
+	}
+	if res.Status != 0 {
+		t.Errorf("expected exit status 0, got %d", res.Status)
+	}
+
+	res = h.Run(t, "env")
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if !reflect.DeepEqual(res.Values, []any{"test env"}) {
+		t.Fatalf("unexpected values %v", res.Values)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	h := New(root, nil)
+
+	res := h.Run(t, "fail")
+	if res.Err == nil || res.Status != 3 {
+		t.Fatalf("expected exit status 3, got %d (%v)", res.Status, res.Err)
+	}
+
+	res = h.Run(t, "greet")
+	if res.Err == nil || res.Status != 1 {
+		t.Fatalf("expected a parse error, got %d (%v)", res.Status, res.Err)
+	}
+
+	res = h.Run(t, "nope")
+	if res.Err == nil || res.Status != 1 {
+		t.Fatalf("expected an unknown command error, got %d (%v)", res.Status, res.Err)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	h := New(root, nil)
+
+	Golden(t, "greet.txt", h.Run(t, "greet", "alice", "bob").Output(t))
+	Golden(t, "greet.json", h.Run(t, "greet", "alice", "--enc=json").Output(t))
+	Golden(t, "greet.help", h.Help(t, "greet"))
+
+	// help requested on the command line also lists the effective values
+	Golden(t, "greet-run.help", h.Run(t, "greet", "--help").Output(t))
+}
@@ -11,6 +11,7 @@ package cmds
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/ipfs/boxo/files"
@@ -91,6 +92,19 @@ type Command struct {
 	// end up returning a cryptic error to the user.
 	Subcommands map[string]*Command
 
+	// Aliases are alternative names the parent command accepts for this
+	// command. Requests are resolved to the name the command is registered
+	// under in Subcommands, and help lists the command once.
+	Aliases []string
+
+	// Hidden commands can be called, but are left out of help texts and
+	// command suggestions.
+	Hidden bool
+
+	// Versions are the API versions serving the command. The commands
+	// below it are limited to the same range.
+	Versions VersionRange
+
 	// NoRemote denotes that a command cannot be executed in a remote environment
 	NoRemote bool
 
@@ -102,6 +116,10 @@ type Command struct {
 
 	// Extra contains a set of other command-specific parameters
 	Extra *Extra
+
+	// OptionGroups constrain which options of the command can be set
+	// together.
+	OptionGroups []OptionGroup
 }
 
 // Status indicates whether this command is active/deprecated/experimental/etc
@@ -197,7 +215,7 @@ func (c *Command) Resolve(pth []string) ([]*Command, error) {
 
 	cmd := c
 	for i, name := range pth {
-		cmd = cmd.Subcommands[name]
+		_, cmd = cmd.Subcommand(name)
 
 		if cmd == nil {
 			pathS := strings.Join(pth[:i], "/")
@@ -210,6 +228,35 @@ func (c *Command) Resolve(pth []string) ([]*Command, error) {
 	return cmds, nil
 }
 
+// Subcommand returns the subcommand of c with the given name or alias, and
+// the name it is registered under. It returns nil if there is none.
+func (c *Command) Subcommand(name string) (string, *Command) {
+	if sub, ok := c.Subcommands[name]; ok {
+		return name, sub
+	}
+	for subName, sub := range c.Subcommands {
+		if slices.Contains(sub.Aliases, name) {
+			return subName, sub
+		}
+	}
+	return "", nil
+}
+
+// CanonicalPath returns path with aliases replaced by the names the commands
+// are registered under.
+func (c *Command) CanonicalPath(path []string) ([]string, error) {
+	res := make([]string, len(path))
+
+	cmd := c
+	for i, name := range path {
+		res[i], cmd = cmd.Subcommand(name)
+		if cmd == nil {
+			return nil, fmt.Errorf("undefined command: %q", strings.Join(path[:i+1], "/"))
+		}
+	}
+	return res, nil
+}
+
 // Get resolves and returns the Command addressed by path
 func (c *Command) Get(path []string) (*Command, error) {
 	cmds, err := c.Resolve(path)
@@ -284,6 +331,35 @@ func (c *Command) DebugValidate() map[string][]error {
 				}
 			}
 		}
+		for _, g := range cm.OptionGroups {
+			for _, name := range g.Names {
+				if _, ok := liveOptions[name]; !ok {
+					errs[path] = append(errs[path], fmt.Errorf("option group refers to unknown option %s", name))
+				}
+			}
+		}
+		for _, option := range cm.Options {
+			if d := OptionDeprecation(option); d != nil && d.ReplacedBy != "" {
+				if _, ok := liveOptions[d.ReplacedBy]; !ok {
+					errs[path] = append(errs[path], fmt.Errorf("option %s is replaced by unknown option %s", option.Name(), d.ReplacedBy))
+				}
+			}
+		}
+		if v := cm.Versions; v.Until != 0 && v.Until <= v.Since {
+			errs[path] = append(errs[path], fmt.Errorf("empty version range %s", v))
+		}
+		names := make(map[string]string, len(cm.Subcommands))
+		for scName := range cm.Subcommands {
+			names[scName] = scName
+		}
+		for scName, sc := range cm.Subcommands {
+			for _, alias := range sc.Aliases {
+				if other, ok := names[alias]; ok && other != scName {
+					errs[path] = append(errs[path], fmt.Errorf("alias %s of subcommand %s is already used by %s", alias, scName, other))
+				}
+				names[alias] = scName
+			}
+		}
 		for scName, sc := range cm.Subcommands {
 			visit(fmt.Sprintf("%s/%s", path, scName), sc)
 		}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -127,6 +128,49 @@ func TestResolving(t *testing.T) {
 	}
 }
 
+func TestResolvingAliases(t *testing.T) {
+	cmdList := &Command{Aliases: []string{"list"}}
+	cmdA := &Command{
+		Aliases: []string{"alpha"},
+		Subcommands: map[string]*Command{
+			"ls":     cmdList,
+			"secret": {Hidden: true},
+		},
+	}
+	cmd := &Command{
+		Subcommands: map[string]*Command{
+			"a": cmdA,
+		},
+	}
+
+	cmds, err := cmd.Resolve([]string{"alpha", "list"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cmds) != 3 || cmds[1] != cmdA || cmds[2] != cmdList {
+		t.Error("Returned command path is different than expected", cmds)
+	}
+
+	pth, err := cmd.CanonicalPath([]string{"alpha", "list"})
+	if err != nil || !reflect.DeepEqual(pth, []string{"a", "ls"}) {
+		t.Errorf("unexpected canonical path %v (%v)", pth, err)
+	}
+	if _, err := cmd.CanonicalPath([]string{"a", "nope"}); err == nil {
+		t.Error("expected an error for an unknown command")
+	}
+	if name, sub := cmdA.Subcommand("secret"); name != "secret" || sub == nil {
+		t.Error("hidden commands should resolve")
+	}
+
+	if errs := cmd.DebugValidate(); errs != nil {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	cmdA.Subcommands["list"] = &Command{}
+	if errs := cmd.DebugValidate(); len(errs["/a"]) != 1 {
+		t.Fatalf("expected an error for the alias collision, got %v", errs)
+	}
+}
+
 func TestWalking(t *testing.T) {
 	cmdA := &Command{
 		Subcommands: map[string]*Command{
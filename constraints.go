@@ -0,0 +1,282 @@
+package cmds
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Constraints restrict the values of an option. They are set with
+// WithAllowed, WithMin, WithMax, WithPattern and WithRequired, and checked
+// whenever a request is created, so commands can rely on them in Run.
+type Constraints struct {
+	// Allowed lists the values the option can take. Values are compared by
+	// their string representation, so that e.g. 1 allows uint(1).
+	Allowed []any
+
+	// Min and Max bound the values of numeric options.
+	Min, Max *float64
+
+	// Pattern must match the values of string options.
+	Pattern *regexp.Regexp
+
+	// Required options must be set.
+	Required bool
+}
+
+// ConstrainedOption is implemented by options whose values can be
+// constrained, like all options created by this package.
+type ConstrainedOption interface {
+	Option
+
+	WithAllowed(values ...any) Option // restricts the option to the given values
+	WithMin(min float64) Option       // sets the minimum of a numeric option
+	WithMax(max float64) Option       // sets the maximum of a numeric option
+	WithPattern(expr string) Option   // sets a regular expression values must match
+	WithRequired() Option             // makes the option required
+	Constraints() Constraints
+}
+
+// WithAllowed restricts opt to the given values.
+func WithAllowed(opt Option, values ...any) Option {
+	return constrained(opt).WithAllowed(values...)
+}
+
+// WithMin sets the minimum of the numeric option opt.
+func WithMin(opt Option, min float64) Option {
+	return constrained(opt).WithMin(min)
+}
+
+// WithMax sets the maximum of the numeric option opt.
+func WithMax(opt Option, max float64) Option {
+	return constrained(opt).WithMax(max)
+}
+
+// WithPattern sets a regular expression the values of the string option opt
+// must match. It panics if expr is not a valid regular expression.
+func WithPattern(opt Option, expr string) Option {
+	return constrained(opt).WithPattern(expr)
+}
+
+// WithRequired makes opt required.
+func WithRequired(opt Option) Option {
+	return constrained(opt).WithRequired()
+}
+
+// OptionConstraints returns the constraints of opt, if it is a
+// ConstrainedOption.
+func OptionConstraints(opt Option) Constraints {
+	if c, ok := opt.(ConstrainedOption); ok {
+		return c.Constraints()
+	}
+	return Constraints{}
+}
+
+// constrained panics if opt can't be constrained.
+func constrained(opt Option) ConstrainedOption {
+	c, ok := opt.(ConstrainedOption)
+	if !ok {
+		panic(fmt.Errorf("option %q can't be constrained", opt.Name()))
+	}
+	return c
+}
+
+// String describes the constraints for help texts, e.g.
+// "Allowed: sha2-256, blake3. Required.".
+func (c Constraints) String() string {
+	var parts []string
+	if len(c.Allowed) > 0 {
+		parts = append(parts, fmt.Sprintf("Allowed: %s.", joinValues(c.Allowed)))
+	}
+	if c.Min != nil {
+		parts = append(parts, fmt.Sprintf("Minimum: %v.", *c.Min))
+	}
+	if c.Max != nil {
+		parts = append(parts, fmt.Sprintf("Maximum: %v.", *c.Max))
+	}
+	if c.Pattern != nil {
+		parts = append(parts, fmt.Sprintf("Pattern: %s.", c.Pattern))
+	}
+	if c.Required {
+		parts = append(parts, "Required.")
+	}
+	return strings.Join(parts, " ")
+}
+
+// check checks the value v of the option name.
+func (c Constraints) check(name string, v any) error {
+	if vs, ok := v.([]string); ok {
+		for _, s := range vs {
+			if err := c.check(name, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(c.Allowed) > 0 {
+		str := fmt.Sprint(v)
+		found := false
+		for _, a := range c.Allowed {
+			if fmt.Sprint(a) == str {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return Errorf(ErrClient, "invalid value %q for option %q: must be one of %s", str, name, joinValues(c.Allowed))
+		}
+	}
+
+	if n, ok := toFloat(v); ok {
+		if c.Min != nil && n < *c.Min {
+			return Errorf(ErrClient, "invalid value %v for option %q: must be at least %v", v, name, *c.Min)
+		}
+		if c.Max != nil && n > *c.Max {
+			return Errorf(ErrClient, "invalid value %v for option %q: must be at most %v", v, name, *c.Max)
+		}
+	}
+
+	if s, ok := v.(string); ok && c.Pattern != nil && !c.Pattern.MatchString(s) {
+		return Errorf(ErrClient, "invalid value %q for option %q: must match %s", s, name, c.Pattern)
+	}
+
+	return nil
+}
+
+func joinValues(vs []any) string {
+	strs := make([]string, len(vs))
+	for i, v := range vs {
+		strs[i] = fmt.Sprint(v)
+	}
+	return strings.Join(strs, ", ")
+}
+
+func toFloat(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+// GroupKind is the way the options of an OptionGroup relate.
+type GroupKind int
+
+const (
+	// ExclusiveGroup options can't be set together.
+	ExclusiveGroup GroupKind = iota
+	// TogetherGroup options must all be set if one of them is.
+	TogetherGroup
+)
+
+// OptionGroup constrains which options of a command can be set together.
+// Names are the names of options of the command or its parents.
+type OptionGroup struct {
+	Kind  GroupKind
+	Names []string
+}
+
+// MutuallyExclusive returns a group of options of which at most one can be
+// set.
+func MutuallyExclusive(names ...string) OptionGroup {
+	return OptionGroup{Kind: ExclusiveGroup, Names: names}
+}
+
+// RequiredTogether returns a group of options that must be set together.
+func RequiredTogether(names ...string) OptionGroup {
+	return OptionGroup{Kind: TogetherGroup, Names: names}
+}
+
+// String describes the group for help texts.
+func (g OptionGroup) String() string {
+	names := make([]string, len(g.Names))
+	for i, name := range g.Names {
+		names[i] = fmt.Sprintf("%q", name)
+	}
+
+	switch g.Kind {
+	case TogetherGroup:
+		return fmt.Sprintf("options %s must be set together", strings.Join(names, ", "))
+	default:
+		return fmt.Sprintf("options %s are mutually exclusive", strings.Join(names, ", "))
+	}
+}
+
+// CheckOptions checks the options of req against the constraints of their
+// definitions and the option groups of the command. Violations are client
+// errors. Call it after FillDefaults or FillLocalDefaults, so that values
+// from all sources are checked, and required options may be set by any of
+// them.
+func (req *Request) CheckOptions() error {
+	return checkOptions(req.Root, req.Path, req.Options, req.Sources, true)
+}
+
+// checkOptions checks opts against the constraints of their definitions and
+// the option groups of the command at path. Required options are only
+// checked if required is set. Values from sources of the SourceDefault kind
+// don't count as set in option groups.
+func checkOptions(root *Command, path []string, opts OptMap, sources map[string]OptionSource, required bool) error {
+	optDefs, err := root.GetOptions(path)
+	if err != nil {
+		return err
+	}
+	cmdPath, err := root.Resolve(path)
+	if err != nil {
+		return err
+	}
+
+	for _, cmd := range cmdPath {
+		for _, opt := range cmd.Options {
+			c := OptionConstraints(opt)
+			v, set := optionValue(opt, opts)
+			if !set {
+				if required && c.Required && opt.Default() == nil {
+					return Errorf(ErrClient, "option %q is required", opt.Name())
+				}
+				continue
+			}
+			if err := c.check(opt.Name(), v); err != nil {
+				return err
+			}
+		}
+
+		for _, g := range cmd.OptionGroups {
+			n := 0
+			for _, name := range g.Names {
+				if opt, ok := optDefs[name]; ok {
+					// a flag set to false is as good as unset, and so are defaults
+					v, set := optionValue(opt, opts)
+					if set && v != false && sources[opt.Name()].Kind != SourceDefault {
+						n++
+					}
+				}
+			}
+
+			switch {
+			case g.Kind == ExclusiveGroup && n > 1,
+				g.Kind == TogetherGroup && n > 0 && n < len(g.Names):
+				return Errorf(ErrClient, "%s", g)
+			}
+		}
+	}
+
+	return nil
+}
+
+// optionValue returns the value of opt set under any of its names.
+func optionValue(opt Option, opts OptMap) (any, bool) {
+	for _, name := range opt.Names() {
+		if v, ok := opts[name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,181 @@
+package cmds
+
+import (
+	"context"
+	"testing"
+)
+
+func TestOptionConstraints(t *testing.T) {
+	root := &Command{
+		Options: []Option{
+			WithAllowed(StringOption("hash", "The hash function.").WithDefault("sha2-256"), "sha2-256", "blake3"),
+			WithMax(WithMin(IntOption("count", "n", "The count."), 1), 10),
+			WithPattern(StringsOption("name", "The names."), "^[a-z]+$"),
+			WithRequired(StringOption("key", "The key.")),
+			BoolOption("json", "JSON output."),
+			BoolOption("xml", "XML output."),
+			StringOption("user", "The user."),
+			StringOption("password", "The password."),
+		},
+		OptionGroups: []OptionGroup{
+			MutuallyExclusive("json", "xml"),
+			RequiredTogether("user", "password"),
+		},
+	}
+
+	for _, tc := range []struct {
+		opts OptMap
+		err  string
+	}{
+		{opts: OptMap{"key": "k"}},
+		{opts: OptMap{"key": "k", "hash": "blake3", "n": "10", "name": []string{"a", "b"}}},
+		{opts: OptMap{"key": "k", "user": "u", "password": "p", "json": true, "xml": false}},
+		{
+			opts: OptMap{},
+			err:  `option "key" is required`,
+		},
+		{
+			opts: OptMap{"key": "k", "hash": "md5"},
+			err:  `invalid value "md5" for option "hash": must be one of sha2-256, blake3`,
+		},
+		{
+			opts: OptMap{"key": "k", "n": "0"},
+			err:  `invalid value 0 for option "count": must be at least 1`,
+		},
+		{
+			opts: OptMap{"key": "k", "count": 11},
+			err:  `invalid value 11 for option "count": must be at most 10`,
+		},
+		{
+			opts: OptMap{"key": "k", "name": []string{"a", "B"}},
+			err:  `invalid value "B" for option "name": must match ^[a-z]+$`,
+		},
+		{
+			opts: OptMap{"key": "k", "json": true, "xml": true},
+			err:  `options "json", "xml" are mutually exclusive`,
+		},
+		{
+			opts: OptMap{"key": "k", "user": "u"},
+			err:  `options "user", "password" must be set together`,
+		},
+	} {
+		req, err := NewRequest(context.Background(), nil, tc.opts, nil, nil, root)
+		if err == nil {
+			err = req.FillDefaults()
+		}
+		if err == nil {
+			err = req.CheckOptions()
+		}
+		if tc.err == "" {
+			if err != nil {
+				t.Errorf("%v: unexpected error: %s", tc.opts, err)
+			}
+			continue
+		}
+
+		e, ok := err.(Error)
+		if !ok || e.Code != ErrClient || e.Message != tc.err {
+			t.Errorf("%v: expected client error %q, got %v", tc.opts, tc.err, err)
+		}
+	}
+}
+
+func TestOptionConstraintsDescription(t *testing.T) {
+	opt := WithRequired(WithAllowed(StringOption("hash", "The hash function").WithDefault("sha2-256"), "sha2-256", "blake3"))
+	if desc, exp := opt.Description(), "The hash function. Default: sha2-256. Allowed: sha2-256, blake3. Required."; desc != exp {
+		t.Fatalf("expected description %q, got %q", exp, desc)
+	}
+
+	opt = WithMax(WithMin(IntOption("count", "The count"), 1), 1.5)
+	if desc, exp := opt.Description(), "The count. Minimum: 1. Maximum: 1.5."; desc != exp {
+		t.Fatalf("expected description %q, got %q", exp, desc)
+	}
+
+	// strings options keep their type
+	opt = WithPattern(StringsOption("name", "The names"), "^a")
+	if _, ok := opt.(*stringsOption); !ok || OptionConstraints(opt).Pattern.String() != "^a" {
+		t.Fatalf("unexpected option %#v", opt)
+	}
+}
+
+func TestDebugValidateOptionGroups(t *testing.T) {
+	root := &Command{
+		Options: []Option{BoolOption("a", "")},
+		Subcommands: map[string]*Command{
+			"sub": {
+				Options:      []Option{BoolOption("b", "")},
+				OptionGroups: []OptionGroup{MutuallyExclusive("a", "b", "c")},
+			},
+		},
+	}
+
+	errs := root.DebugValidate()
+	if len(errs["/sub"]) != 1 {
+		t.Fatalf("expected an error for the unknown option, got %v", errs)
+	}
+}
+
+func TestOptionConstraintsLocalSources(t *testing.T) {
+	root := &Command{
+		Options: []Option{
+			WithRequired(StringOption("key", "The key.")),
+			WithAllowed(WithEnv(StringOption("hash", "The hash function."), "TEST_HASH"), "sha2-256", "blake3"),
+			StringOption("format", "The format.").WithDefault("text"),
+			StringOption("template", "The template."),
+		},
+		OptionGroups: []OptionGroup{MutuallyExclusive("format", "template")},
+	}
+	check := func(opts OptMap, cfg *Config) error {
+		req, err := NewRequest(context.Background(), nil, opts, nil, nil, root)
+		if err != nil {
+			return err
+		}
+		req.Config = cfg
+		if err := req.FillLocalDefaults(); err != nil {
+			return err
+		}
+		return req.CheckOptions()
+	}
+
+	cfg := &Config{Values: map[string]any{"key": "k"}}
+	if err := check(OptMap{}, cfg); err != nil {
+		t.Errorf("expected the config file to set the required option, got %v", err)
+	}
+	if err := check(OptMap{"template": "{{.}}"}, cfg); err != nil {
+		t.Errorf("defaults should not count in option groups, got %v", err)
+	}
+
+	t.Setenv("TEST_HASH", "md5")
+	if err := check(OptMap{}, cfg); err == nil || err.(Error).Code != ErrClient {
+		t.Errorf("expected values of the environment to be checked, got %v", err)
+	}
+}
+
+// plainOption implements only the methods of Option, like options defined
+// outside of this package.
+type plainOption struct {
+	Option
+}
+
+func TestOptionConstraintsPlainOption(t *testing.T) {
+	opt := plainOption{IntOption("count", "The count.")}
+	root := &Command{Options: []Option{opt}}
+
+	req, err := NewRequest(context.Background(), nil, OptMap{"count": 5}, nil, nil, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := req.CheckOptions(); err != nil {
+		t.Fatal(err)
+	}
+	if c := OptionConstraints(opt); c.Min != nil || c.Required {
+		t.Fatalf("expected no constraints, got %+v", c)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic constraining the option")
+		}
+	}()
+	WithMin(opt, 1)
+}
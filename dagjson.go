@@ -0,0 +1,119 @@
+package cmds
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+// DAGJSONEncoder encodes values as DAG-JSON: map keys are sorted, CIDs are
+// encoded as {"/": "<cid>"} links and byte strings as
+// {"/": {"bytes": "<base64>"}}.
+//
+// See https://ipld.io/specs/codecs/dag-json/spec/.
+type DAGJSONEncoder struct {
+	enc *json.Encoder
+}
+
+// NewDAGJSONEncoder returns an encoder writing DAG-JSON to w.
+func NewDAGJSONEncoder(w io.Writer) *DAGJSONEncoder {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &DAGJSONEncoder{enc: enc}
+}
+
+func (e *DAGJSONEncoder) Encode(v any) error {
+	tree, err := valueTree(v)
+	if err != nil {
+		return err
+	}
+
+	// encoding/json sorts map keys, which gives us the canonical key order
+	return e.enc.Encode(dagJSONTree(tree))
+}
+
+func dagJSONTree(v any) any {
+	switch v := v.(type) {
+	case []byte:
+		return map[string]any{"/": map[string]any{"bytes": base64.RawStdEncoding.EncodeToString(v)}}
+	case cid.Cid:
+		return map[string]any{"/": v.String()}
+	case []any:
+		l := make([]any, len(v))
+		for i := range v {
+			l[i] = dagJSONTree(v[i])
+		}
+		return l
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k := range v {
+			m[k] = dagJSONTree(v[k])
+		}
+		return m
+	default:
+		return v
+	}
+}
+
+// DAGJSONDecoder decodes DAG-JSON values.
+type DAGJSONDecoder struct {
+	dec *json.Decoder
+}
+
+// NewDAGJSONDecoder returns a decoder reading DAG-JSON from r.
+func NewDAGJSONDecoder(r io.Reader) *DAGJSONDecoder {
+	return &DAGJSONDecoder{dec: json.NewDecoder(r)}
+}
+
+func (d *DAGJSONDecoder) Decode(v any) error {
+	var raw json.RawMessage
+	if err := d.dec.Decode(&raw); err != nil {
+		return err
+	}
+
+	tree, err := jsonTree(raw)
+	if err != nil {
+		return err
+	}
+	tree, err = treeFromDAGJSON(tree)
+	if err != nil {
+		return err
+	}
+	return unmarshalTree(tree, v)
+}
+
+func treeFromDAGJSON(v any) (any, error) {
+	switch v := v.(type) {
+	case []any:
+		for i := range v {
+			var err error
+			if v[i], err = treeFromDAGJSON(v[i]); err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+	case map[string]any:
+		if slash, ok := v["/"]; ok && len(v) == 1 {
+			switch slash := slash.(type) {
+			case string:
+				return cid.Decode(slash)
+			case map[string]any:
+				if b, ok := slash["bytes"].(string); ok && len(slash) == 1 {
+					return base64.RawStdEncoding.DecodeString(b)
+				}
+			}
+		}
+
+		for k := range v {
+			var err error
+			if v[k], err = treeFromDAGJSON(v[k]); err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+	default:
+		return v, nil
+	}
+}
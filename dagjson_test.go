@@ -0,0 +1,45 @@
+package cmds
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestDAGJSON(t *testing.T) {
+	c := mustCid(t, "bafkqaaa")
+
+	buf := new(bytes.Buffer)
+	err := NewDAGJSONEncoder(buf).Encode(linkTestObj{Name: "<a>", Link: c, Data: []byte{1, 2}, Size: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := `{"Data":{"/":{"bytes":"AQI"}},"Link":{"/":"bafkqaaa"},"Name":"<a>","size":3}` + "\n"
+	if buf.String() != exp {
+		t.Fatalf("expected %s, got %s", exp, buf.String())
+	}
+
+	var v linkTestObj
+	if err := NewDAGJSONDecoder(buf).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, linkTestObj{Name: "<a>", Link: c, Data: []byte{1, 2}, Size: 3}) {
+		t.Fatalf("unexpected value %#v", v)
+	}
+}
+
+func TestDAGJSONMaybeError(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := NewDAGJSONEncoder(buf).Encode(Error{Message: "some error"}); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &MaybeError{Value: &Foo{}}
+	if err := NewDAGJSONDecoder(buf).Decode(m); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Get(); err == nil || err.Error() != "some error" {
+		t.Fatalf("expected error %q, got %v", "some error", err)
+	}
+}
@@ -0,0 +1,197 @@
+package cmds
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Deprecation describes an option or argument that is being phased out.
+// Using a deprecated option or argument adds a warning to the request, and
+// using a removed one fails the request with a client error.
+type Deprecation struct {
+	// ReplacedBy names the option that replaces a deprecated option. Values
+	// set with the deprecated option are used for the replacement, unless
+	// it is set itself. The replacement must have the same type.
+	ReplacedBy string
+
+	// RemovedIn is the version the option or argument is, or will be,
+	// removed in.
+	RemovedIn string
+
+	// Removed options and arguments can't be used anymore.
+	Removed bool
+
+	// Note explains what to do instead, e.g. "use --dereference-symlinks
+	// instead", if there is no replacement option.
+	Note string
+}
+
+// DeprecatableOption is implemented by options that can be deprecated, like
+// all options created by this package.
+type DeprecatableOption interface {
+	Option
+
+	WithDeprecation(d Deprecation) Option // marks the option as deprecated or removed
+	Deprecation() *Deprecation
+}
+
+// WithDeprecation marks opt as deprecated or removed. It panics if opt isn't
+// a DeprecatableOption.
+func WithDeprecation(opt Option, d Deprecation) Option {
+	do, ok := opt.(DeprecatableOption)
+	if !ok {
+		panic(fmt.Errorf("option %q can't be deprecated", opt.Name()))
+	}
+	return do.WithDeprecation(d)
+}
+
+// OptionDeprecation returns the deprecation of opt, or nil if it isn't
+// deprecated.
+func OptionDeprecation(opt Option) *Deprecation {
+	if do, ok := opt.(DeprecatableOption); ok {
+		return do.Deprecation()
+	}
+	return nil
+}
+
+// message describes using what, e.g. `option "deref"`, with d.
+func (d *Deprecation) message(what string) string {
+	var b strings.Builder
+	if d.Removed {
+		fmt.Fprintf(&b, "%s was removed", what)
+		if d.RemovedIn != "" {
+			fmt.Fprintf(&b, " in %s", d.RemovedIn)
+		}
+	} else {
+		fmt.Fprintf(&b, "%s is deprecated", what)
+		if d.RemovedIn != "" {
+			fmt.Fprintf(&b, " and will be removed in %s", d.RemovedIn)
+		}
+	}
+
+	switch {
+	case d.ReplacedBy != "":
+		fmt.Fprintf(&b, ", use %q instead", d.ReplacedBy)
+	case d.Note != "":
+		fmt.Fprintf(&b, ": %s", d.Note)
+	}
+	return b.String()
+}
+
+// String describes d for help texts, e.g. "DEPRECATED: use --cid-base
+// instead." or "REMOVED in v0.5.0.".
+func (d *Deprecation) String() string {
+	var b strings.Builder
+	if d.Removed {
+		b.WriteString("REMOVED")
+		if d.RemovedIn != "" {
+			fmt.Fprintf(&b, " in %s", d.RemovedIn)
+		}
+	} else {
+		b.WriteString("DEPRECATED")
+		if d.RemovedIn != "" {
+			fmt.Fprintf(&b, ", will be removed in %s", d.RemovedIn)
+		}
+	}
+
+	switch {
+	case d.ReplacedBy != "":
+		fmt.Fprintf(&b, ": use --%s instead", d.ReplacedBy)
+	case d.Note != "":
+		fmt.Fprintf(&b, ": %s", d.Note)
+	}
+	b.WriteString(".")
+	return b.String()
+}
+
+// CheckDeprecations checks the request for deprecated options and
+// arguments, and appends a warning for each of them to Warnings. Removed
+// options and arguments are client errors.
+func (req *Request) CheckDeprecations() error {
+	cmdPath, err := req.Root.Resolve(req.Path)
+	if err != nil {
+		return err
+	}
+
+	for _, cmd := range cmdPath {
+		for _, opt := range cmd.Options {
+			d := OptionDeprecation(opt)
+			if d == nil {
+				continue
+			}
+			if _, set := optionValue(opt, req.Options); !set {
+				continue
+			}
+			if src, ok := req.OptionSource(opt.Name()); ok && src.Kind != SourceFlag {
+				// only warn about options the caller set
+				continue
+			}
+
+			msg := d.message(fmt.Sprintf("option %q", opt.Name()))
+			if d.Removed {
+				return Errorf(ErrClient, "%s", msg)
+			}
+			req.Warnings = append(req.Warnings, msg)
+		}
+	}
+
+	for _, argDef := range req.usedArguments() {
+		if d := argDef.Deprecation; d != nil {
+			msg := d.message(fmt.Sprintf("argument %q", argDef.Name))
+			if d.Removed {
+				return Errorf(ErrClient, "%s", msg)
+			}
+			req.Warnings = append(req.Warnings, msg)
+		}
+	}
+
+	return nil
+}
+
+// usedArguments returns the definitions of the arguments passed in the
+// request. String arguments are matched to definitions in order, file
+// arguments are used if the request has files. Files aren't read, as they
+// may be streamed.
+func (req *Request) usedArguments() []Argument {
+	if req.Command == nil {
+		return nil
+	}
+
+	var used []Argument
+	n := len(req.Arguments)
+	for _, argDef := range req.Command.Arguments {
+		switch {
+		case argDef.Type == ArgFile:
+			if req.Files != nil {
+				used = append(used, argDef)
+			}
+		case n > 0:
+			used = append(used, argDef)
+			if argDef.Variadic {
+				n = 0
+			} else {
+				n--
+			}
+		}
+	}
+	return used
+}
+
+// deprecatedValue returns the value set for a deprecated option replaced by
+// opt, and the name of that option.
+func (req *Request) deprecatedValue(opt Option) (any, string, bool) {
+	optDefs, err := req.Root.GetOptions(req.Path)
+	if err != nil {
+		return nil, "", false
+	}
+
+	for _, def := range optDefs {
+		if d := OptionDeprecation(def); d == nil || d.Removed || d.ReplacedBy != opt.Name() {
+			continue
+		}
+		if v, set := optionValue(def, req.Options); set {
+			return v, def.Name(), true
+		}
+	}
+	return nil, "", false
+}
@@ -0,0 +1,110 @@
+package cmds
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func deprecationRoot() *Command {
+	return &Command{
+		Options: []Option{
+			WithRequired(StringOption("cid-base", "The multibase of CIDs.")),
+			WithDeprecation(StringOption("base", "The multibase of CIDs."), Deprecation{ReplacedBy: "cid-base", RemovedIn: "v1.0.0"}),
+			WithDeprecation(BoolOption("old", "Use the old format."), Deprecation{Removed: true, RemovedIn: "v0.9.0", Note: "the old format is gone"}),
+		},
+		Arguments: []Argument{
+			StringArg("path", false, false, "The path.").WithDeprecation(Deprecation{Note: "pass --cid-base instead"}),
+		},
+	}
+}
+
+func checkDeprecations(opts OptMap, args []string) (*Request, error) {
+	req, err := NewRequest(context.Background(), nil, opts, args, nil, deprecationRoot())
+	if err != nil {
+		return nil, err
+	}
+	if err := req.FillDefaults(); err != nil {
+		return nil, err
+	}
+	if err := req.CheckOptions(); err != nil {
+		return nil, err
+	}
+	return req, req.CheckDeprecations()
+}
+
+func TestDeprecatedOptions(t *testing.T) {
+	req, err := checkDeprecations(OptMap{"base": "base32"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := req.Options["cid-base"]; v != "base32" {
+		t.Errorf("expected the value of the deprecated option to be used, got %v", v)
+	}
+	if src, _ := req.OptionSource("cid-base"); src.String() != "flag --base" {
+		t.Errorf("unexpected source %s", src)
+	}
+	exp := []string{`option "base" is deprecated and will be removed in v1.0.0, use "cid-base" instead`}
+	if !reflect.DeepEqual(req.Warnings, exp) {
+		t.Errorf("expected warnings %q, got %q", exp, req.Warnings)
+	}
+
+	// the replacement wins if both are set
+	req, err = checkDeprecations(OptMap{"base": "base32", "cid-base": "base58btc"}, []string{"/a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := req.Options["cid-base"]; v != "base58btc" {
+		t.Errorf("expected the value of the replacement, got %v", v)
+	}
+	exp = append(exp, `argument "path" is deprecated: pass --cid-base instead`)
+	if !reflect.DeepEqual(req.Warnings, exp) {
+		t.Errorf("expected warnings %q, got %q", exp, req.Warnings)
+	}
+
+	_, err = checkDeprecations(OptMap{"cid-base": "base32", "old": true}, nil)
+	if e, ok := err.(Error); !ok || e.Code != ErrClient || e.Message != `option "old" was removed in v0.9.0: the old format is gone` {
+		t.Errorf("expected a client error, got %v", err)
+	}
+}
+
+func TestDeprecationDescription(t *testing.T) {
+	root := deprecationRoot()
+	for i, exp := range []string{
+		"The multibase of CIDs. Required.",
+		"DEPRECATED, will be removed in v1.0.0: use --cid-base instead. The multibase of CIDs.",
+		"REMOVED in v0.9.0: the old format is gone. Use the old format.",
+	} {
+		if desc := root.Options[i].Description(); desc != exp {
+			t.Errorf("expected description %q, got %q", exp, desc)
+		}
+	}
+
+	if desc := OptionDerefArgs.Description(); !strings.HasPrefix(desc, "DEPRECATED: use --dereference-symlinks instead.") {
+		t.Errorf("unexpected description %q", desc)
+	}
+}
+
+func TestDebugValidateDeprecation(t *testing.T) {
+	root := &Command{
+		Options: []Option{
+			WithDeprecation(StringOption("base", ""), Deprecation{ReplacedBy: "cid-base"}),
+		},
+	}
+
+	errs := root.DebugValidate()
+	if len(errs[""]) != 1 {
+		t.Fatalf("expected an error for the unknown replacement, got %v", errs)
+	}
+}
+
+func TestDeprecationPlainOption(t *testing.T) {
+	opt := plainOption{StringOption("base", "")}
+	if d := OptionDeprecation(opt); d != nil {
+		t.Fatalf("expected no deprecation, got %v", d)
+	}
+	if d := OptionDeprecation(WithDeprecation(StringOption("base", ""), Deprecation{Removed: true})); d == nil || !d.Removed {
+		t.Fatalf("expected a removed option, got %v", d)
+	}
+}
@@ -36,6 +36,40 @@ const (
 	Text        = "text"
 	TextNewline = "textnl"
 
+	// NDJSON emits one JSON value per line (newline-delimited JSON, also
+	// known as JSON Lines).
+	NDJSON = "ndjson"
+
+	// JSONSeq emits an RFC 7464 JSON text sequence, where every value is
+	// prefixed with an ASCII record separator.
+	JSONSeq = "json-seq"
+
+	// CBOR emits a sequence of CBOR data items (RFC 8742), encoding CIDs
+	// as tag 42 links.
+	CBOR = "cbor"
+
+	// DAGJSON emits DAG-JSON, encoding CIDs and byte strings as links.
+	DAGJSON = "dag-json"
+
+	// YAML emits every value as a separate YAML document.
+	YAML = "yaml"
+
+	// TOML emits every value as a separate TOML document. Only values
+	// encoding to a table are supported.
+	TOML = "toml"
+
+	// Table emits values as rows of a table, with columns derived from the
+	// command's Type.
+	Table = "table"
+
+	// CSV emits values as CSV records, with columns derived from the
+	// command's Type.
+	CSV = "csv"
+
+	// EventStream emits values as Server-Sent Events, for consumption by
+	// an EventSource.
+	EventStream = "event-stream"
+
 	// OctetStream is a generic binary pass-through encoding.
 	// Use SetContentType() to specify the actual MIME type if different
 	// from application/octet-stream.
@@ -52,6 +86,30 @@ var Decoders = map[EncodingType]func(w io.Reader) Decoder{
 	JSON: func(r io.Reader) Decoder {
 		return json.NewDecoder(r)
 	},
+	NDJSON: func(r io.Reader) Decoder {
+		return json.NewDecoder(r)
+	},
+	JSONSeq: func(r io.Reader) Decoder {
+		return NewJSONSeqDecoder(r)
+	},
+	EventStream: func(r io.Reader) Decoder {
+		return NewEventStreamDecoder(r)
+	},
+	CBOR: func(r io.Reader) Decoder {
+		return NewCBORDecoder(r)
+	},
+	DAGJSON: func(r io.Reader) Decoder {
+		return NewDAGJSONDecoder(r)
+	},
+	Protobuf: func(r io.Reader) Decoder {
+		return NewProtobufDecoder(r)
+	},
+	YAML: func(r io.Reader) Decoder {
+		return NewYAMLDecoder(r)
+	},
+	TOML: func(r io.Reader) Decoder {
+		return NewTOMLDecoder(r)
+	},
 }
 
 type EncoderFunc func(req *Request) func(w io.Writer) Encoder
@@ -64,6 +122,37 @@ var Encoders = EncoderMap{
 	JSON: func(req *Request) func(io.Writer) Encoder {
 		return func(w io.Writer) Encoder { return json.NewEncoder(w) }
 	},
+	NDJSON: func(req *Request) func(io.Writer) Encoder {
+		return func(w io.Writer) Encoder { return json.NewEncoder(w) }
+	},
+	JSONSeq: func(req *Request) func(io.Writer) Encoder {
+		return func(w io.Writer) Encoder { return NewJSONSeqEncoder(w) }
+	},
+	EventStream: func(req *Request) func(io.Writer) Encoder {
+		return func(w io.Writer) Encoder { return NewEventStreamEncoder(w) }
+	},
+	CBOR: func(req *Request) func(io.Writer) Encoder {
+		return func(w io.Writer) Encoder { return NewCBOREncoder(w) }
+	},
+	DAGJSON: func(req *Request) func(io.Writer) Encoder {
+		return func(w io.Writer) Encoder { return NewDAGJSONEncoder(w) }
+	},
+	// Protobuf only supports values implementing proto.Message.
+	Protobuf: func(req *Request) func(io.Writer) Encoder {
+		return func(w io.Writer) Encoder { return NewProtobufEncoder(w) }
+	},
+	YAML: func(req *Request) func(io.Writer) Encoder {
+		return func(w io.Writer) Encoder { return NewYAMLEncoder(w) }
+	},
+	TOML: func(req *Request) func(io.Writer) Encoder {
+		return func(w io.Writer) Encoder { return NewTOMLEncoder(w) }
+	},
+	Table: func(req *Request) func(io.Writer) Encoder {
+		return func(w io.Writer) Encoder { return NewTableEncoder(req, w) }
+	},
+	CSV: func(req *Request) func(io.Writer) Encoder {
+		return func(w io.Writer) Encoder { return NewCSVEncoder(req, w) }
+	},
 	Text: func(req *Request) func(io.Writer) Encoder {
 		return func(w io.Writer) Encoder { return TextEncoder{w: w} }
 	},
@@ -171,21 +260,42 @@ func (e TextEncoder) Encode(v any) error {
 }
 
 // GetEncoder takes a request and returns returns the encoding type and the encoder.
+// If the request sets a filter, the encoder applies it to every value.
 func GetEncoder(req *Request, w io.Writer, def EncodingType) (encType EncodingType, enc Encoder, err error) {
 	encType = GetEncoding(req, def)
 
+	filter, err := GetFilter(req)
+	if err != nil {
+		return encType, nil, err
+	}
+
 	var (
 		fn EncoderFunc
 		ok bool
 	)
-	if req.Command != nil {
+	// filtered values no longer have the command's Type, so the command's
+	// own encoders can't be used for them.
+	if req.Command != nil && filter == nil {
 		fn, ok = req.Command.Encoders[encType]
 	}
 	if !ok {
-		fn, ok = Encoders[encType]
+		if filter != nil && encType == Text {
+			fn, ok = Encoders[TextNewline]
+		} else {
+			fn, ok = Encoders[encType]
+		}
 	}
 	if !ok {
 		return encType, nil, Errorf(ErrClient, "invalid encoding: %s", encType)
 	}
-	return encType, fn(req)(w), nil
+
+	enc = fn(req)(w)
+	if filter != nil {
+		enc = &filterEncoder{
+			filter: filter,
+			enc:    enc,
+			text:   encType == Text || encType == TextNewline,
+		}
+	}
+	return encType, enc, nil
 }
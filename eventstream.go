@@ -0,0 +1,158 @@
+package cmds
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrorEvent is the name of the events errors are sent as in an event
+// stream.
+const ErrorEvent = "error"
+
+// Event is a value with the metadata of a Server-Sent Event. Commands
+// supporting resumption (see OptionLastEventID) emit their values as Events
+// to set their IDs. Other encodings only encode the Value, as long as they
+// use the value's JSON encoding.
+type Event struct {
+	// ID is the ID of the event. If it is empty, the event stream encoder
+	// numbers events sequentially.
+	ID string
+
+	// Name is the name of the event. Unnamed events are "message" events
+	// to an EventSource.
+	Name string
+
+	Value any
+}
+
+func (e Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.Value)
+}
+
+// EventStreamEncoder encodes values as a stream of Server-Sent Events, as
+// read by an EventSource in browsers. Every value becomes an event with its
+// JSON encoding as data, errors become ErrorEvent events and progress
+// reports ProgressEvent events.
+type EventStreamEncoder struct {
+	w   io.Writer
+	seq uint64
+}
+
+// NewEventStreamEncoder returns an encoder writing a text/event-stream to w.
+func NewEventStreamEncoder(w io.Writer) *EventStreamEncoder {
+	return &EventStreamEncoder{w: w}
+}
+
+func (e *EventStreamEncoder) Encode(v any) error {
+	var id, name string
+	switch ev := v.(type) {
+	case Event:
+		id, name, v = ev.ID, ev.Name, ev.Value
+	case *Event:
+		id, name, v = ev.ID, ev.Name, ev.Value
+	case Error, *Error:
+		name = ErrorEvent
+	case Progress, *Progress:
+		name = ProgressEvent
+	}
+
+	if strings.ContainsAny(id, "\r\n\x00") || strings.ContainsAny(name, "\r\n") {
+		return fmt.Errorf("invalid event id %q or name %q", id, name)
+	}
+	if id == "" && name != ErrorEvent && name != ProgressEvent {
+		e.seq++
+		id = strconv.FormatUint(e.seq, 10)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	if name != "" {
+		fmt.Fprintf(buf, "event: %s\n", name)
+	}
+	if id != "" {
+		fmt.Fprintf(buf, "id: %s\n", id)
+	}
+	// MarshalJSON implementations may return indented JSON
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		fmt.Fprintf(buf, "data: %s\n", line)
+	}
+	buf.WriteString("\n")
+
+	_, err = e.w.Write(buf.Bytes())
+	return err
+}
+
+// EventStreamDecoder decodes the data of the events of a text/event-stream.
+// Both errors and values are decoded from their JSON encoding, so the event
+// names are not needed to tell them apart.
+type EventStreamDecoder struct {
+	r      *bufio.Reader
+	lastID string
+}
+
+// NewEventStreamDecoder returns a decoder reading Server-Sent Events from r.
+func NewEventStreamDecoder(r io.Reader) *EventStreamDecoder {
+	return &EventStreamDecoder{r: bufio.NewReader(r)}
+}
+
+// LastEventID returns the ID of the last event decoded, to resume the
+// stream with.
+func (d *EventStreamDecoder) LastEventID() string {
+	return d.lastID
+}
+
+func (d *EventStreamDecoder) Decode(v any) error {
+	var (
+		data    [][]byte
+		hasData bool
+	)
+
+	for {
+		line, err := d.r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) == 0 {
+			if hasData {
+				break
+			}
+			if err == io.EOF {
+				return io.EOF
+			}
+			continue
+		}
+
+		field, value, _ := bytes.Cut(line, []byte(":"))
+		value = bytes.TrimPrefix(value, []byte(" "))
+		switch string(field) {
+		case "":
+			// comment, e.g. a keepalive
+		case "data":
+			data = append(data, value)
+			hasData = true
+		case "id":
+			d.lastID = string(value)
+		}
+
+		if err == io.EOF {
+			if !hasData {
+				return io.EOF
+			}
+			// dispatch the last event even without a blank line
+			break
+		}
+	}
+
+	return json.Unmarshal(bytes.Join(data, []byte("\n")), v)
+}
@@ -0,0 +1,69 @@
+package cmds
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEventStreamEncoder(t *testing.T) {
+	buf := new(bytes.Buffer)
+	enc := NewEventStreamEncoder(buf)
+	for _, v := range []any{
+		&Foo{1},
+		Event{ID: "abc", Name: "update", Value: "x"},
+		&Foo{2},
+		&Error{Message: "failed", Code: ErrClient},
+	} {
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exp := "id: 1\ndata: {\"Bar\":1}\n\n" +
+		"event: update\nid: abc\ndata: \"x\"\n\n" +
+		"id: 2\ndata: {\"Bar\":2}\n\n" +
+		"event: error\ndata: {\"Message\":\"failed\",\"Code\":1,\"Type\":\"error\"}\n\n"
+	if buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+
+	if err := enc.Encode(Event{ID: "a\nb"}); err == nil {
+		t.Fatal("expected error encoding an id with a line feed")
+	}
+}
+
+func TestEventStreamDecoder(t *testing.T) {
+	stream := ": keepalive\n\n" +
+		"id: 1\ndata: {\"Bar\":1}\n\n" +
+		"event: update\r\nid: 7\r\ndata:{\"Bar\":\r\ndata: 2}\r\n\r\n" +
+		": keepalive\n\n" +
+		"event: error\ndata: {\"Message\":\"failed\",\"Code\":1,\"Type\":\"error\"}"
+
+	dec := NewEventStreamDecoder(strings.NewReader(stream))
+	for _, exp := range []int{1, 2} {
+		var foo Foo
+		if err := dec.Decode(&foo); err != nil {
+			t.Fatal(err)
+		}
+		if foo.Bar != exp {
+			t.Fatalf("expected %d, got %d", exp, foo.Bar)
+		}
+	}
+	if id := dec.LastEventID(); id != "7" {
+		t.Fatalf("expected last event id 7, got %q", id)
+	}
+
+	m := &MaybeError{Value: &Foo{}}
+	if err := dec.Decode(m); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Get(); err == nil || err.Error() != "failed" {
+		t.Fatalf("expected error, got %v", err)
+	}
+
+	if err := dec.Decode(&Foo{}); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
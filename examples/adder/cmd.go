@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"io"
 	"strconv"
-	"strings"
 	"time"
 
 	cmds "github.com/ipfs/go-ipfs-cmds"
@@ -124,11 +123,8 @@ var RootCmd = &cmds.Command{
 			Type: &AddStatus{},
 			PostRun: cmds.PostRunMap{
 				cmds.CLI: func(res cmds.Response, re cmds.ResponseEmitter) error {
+					lines := re.(cli.LineUpdater)
 					defer re.Close()
-					defer fmt.Println()
-
-					// length of line at last iteration
-					var lastLen int
 
 					for {
 						v, err := res.Next()
@@ -139,18 +135,49 @@ var RootCmd = &cmds.Command{
 							return err
 						}
 
-						fmt.Print("\r" + strings.Repeat(" ", lastLen))
-
+						// the line is updated in place on a terminal, and
+						// only the final sum is printed otherwise
 						s := v.(*AddStatus)
 						if s.Left > 0 {
-							lastLen, _ = fmt.Printf("\rcalculation sum... current: %d; left: %d", s.Current, s.Left)
+							lines.UpdateLine(fmt.Sprintf("calculation sum... current: %d; left: %d", s.Current, s.Left))
 						} else {
-							lastLen, _ = fmt.Printf("\rsum is %d.", s.Current)
+							lines.UpdateLine(fmt.Sprintf("sum is %d.", s.Current))
 						}
 					}
 				},
 			},
 		},
+		// report progress separately from the result
+		"progressAdd": {
+			Arguments: []cmds.Argument{
+				cmds.StringArg("summands", true, true, "values that are supposed to be summed"),
+			},
+			Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+				sum := 0
+
+				for i, str := range req.Arguments {
+					num, err := strconv.Atoi(str)
+					if err != nil {
+						return err
+					}
+
+					sum += num
+					err = cmds.EmitProgress(re, cmds.Progress{
+						Done:    uint64(i + 1),
+						Total:   uint64(len(req.Arguments)),
+						Phase:   "adding",
+						Message: fmt.Sprintf("current sum: %d", sum),
+					})
+					if err != nil {
+						return err
+					}
+
+					time.Sleep(200 * time.Millisecond)
+				}
+
+				return cmds.EmitOnce(re, fmt.Sprintf("total: %d", sum))
+			},
+		},
 		// how to set program's return value
 		"exitAdd": {
 			Arguments: []cmds.Argument{
@@ -183,10 +210,7 @@ var RootCmd = &cmds.Command{
 			PostRun: cmds.PostRunMap{
 				cmds.CLI: func(res cmds.Response, re cmds.ResponseEmitter) error {
 					clire := re.(cli.ResponseEmitter)
-					defer fmt.Println()
-
-					// length of line at last iteration
-					var lastLen int
+					lines := re.(cli.LineUpdater)
 
 					var exit int
 					defer func() {
@@ -202,13 +226,11 @@ var RootCmd = &cmds.Command{
 							return err
 						}
 
-						fmt.Print("\r" + strings.Repeat(" ", lastLen))
-
 						s := v.(*AddStatus)
 						if s.Left > 0 {
-							lastLen, _ = fmt.Printf("\rcalculation sum... current: %d; left: %d", s.Current, s.Left)
+							lines.UpdateLine(fmt.Sprintf("calculation sum... current: %d; left: %d", s.Current, s.Left))
 						} else {
-							lastLen, _ = fmt.Printf("\rsum is %d.", s.Current)
+							lines.UpdateLine(fmt.Sprintf("sum is %d.", s.Current))
 							exit = s.Current
 						}
 					}
@@ -0,0 +1,637 @@
+package cmds
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Filter is a compiled filter expression, applied to every emitted value
+// when the FilterOpt option is set. The expression language is a small
+// subset of jq:
+//
+//	.                  the value itself
+//	.Name, ."some key"  a field of an object (null if missing)
+//	.[2], .[-1]        an element of an array
+//	.[]                every element of an array or value of an object
+//	.Links[].Hash      paths can be chained
+//	{Name, Size: .Stat.Size}
+//	                   an object built from the given fields
+//	select(.Size > 10) the value, if the condition holds
+//	a | b              the results of a, filtered by b
+//
+// Conditions compare paths and literals (numbers, strings, true, false and
+// null) with ==, !=, <, <=, > and >=. A path on its own is true unless it is
+// false or null.
+//
+// Filters operate on the values as they are encoded to JSON, so field names
+// follow the json struct tags. As a filter can produce any number of
+// results, each of them is encoded separately.
+type Filter struct {
+	expr string
+	f    filterFunc
+}
+
+type filterFunc func(v any) ([]any, error)
+
+// ParseFilter compiles a filter expression. Syntax errors are returned as
+// client errors.
+func ParseFilter(expr string) (*Filter, error) {
+	p := &filterParser{expr: expr}
+	if err := p.lex(); err != nil {
+		return nil, Errorf(ErrClient, "invalid filter %q: %s", expr, err)
+	}
+
+	f, err := p.parsePipe()
+	if err == nil && !p.done() {
+		err = fmt.Errorf("unexpected %q", p.peek().text)
+	}
+	if err != nil {
+		return nil, Errorf(ErrClient, "invalid filter %q: %s", expr, err)
+	}
+
+	return &Filter{expr: expr, f: f}, nil
+}
+
+// GetFilter returns the filter set in the FilterOpt option of a request, or
+// nil if there is none. Options of the command that happen to be named
+// FilterOpt are left alone.
+func GetFilter(req *Request) (*Filter, error) {
+	expr := filterExpr(req)
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+	return ParseFilter(expr)
+}
+
+// UsesFilterOption reports whether the FilterOpt option of req is
+// OptionFilter, rather than an option of the command with the same name.
+func UsesFilterOption(req *Request) bool {
+	if req.Root != nil {
+		if opts, err := req.Root.GetOptions(req.Path); err == nil {
+			return opts[FilterOpt] == OptionFilter
+		}
+	}
+
+	// without the command tree, only the command's own options are known
+	if req.Command != nil {
+		for _, opt := range req.Command.Options {
+			if slices.Contains(opt.Names(), FilterOpt) {
+				return opt == OptionFilter
+			}
+		}
+	}
+	return true
+}
+
+// filterExpr returns the filter expression set in req, if any.
+func filterExpr(req *Request) string {
+	if !UsesFilterOption(req) {
+		return ""
+	}
+	expr, _ := req.Options[FilterOpt].(string)
+	return expr
+}
+
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// Apply runs the filter on v and returns the results.
+func (f *Filter) Apply(v any) ([]any, error) {
+	tree, err := valueTree(v)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := f.f(tree)
+	if err != nil {
+		return nil, Errorf(ErrClient, "filter %q: %s", f.expr, err)
+	}
+	return res, nil
+}
+
+// filterEncoder applies a filter to every value before encoding its results.
+type filterEncoder struct {
+	filter *Filter
+	enc    Encoder
+
+	// text makes the encoder write strings as they are and all other values
+	// as JSON, like jq's raw output.
+	text bool
+}
+
+func (e *filterEncoder) Encode(v any) error {
+	res, err := e.filter.Apply(v)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range res {
+		if _, isString := r.(string); e.text && !isString {
+			data, err := json.Marshal(r)
+			if err != nil {
+				return err
+			}
+			r = string(data)
+		}
+
+		if err := e.enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type tokenKind int
+
+const (
+	tokPunct tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	val  any // parsed value of strings and numbers
+}
+
+type filterParser struct {
+	expr   string
+	tokens []token
+	i      int
+}
+
+func (p *filterParser) lex() error {
+	s := p.expr
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(s[i:], "==") || strings.HasPrefix(s[i:], "!=") ||
+			strings.HasPrefix(s[i:], "<=") || strings.HasPrefix(s[i:], ">="):
+			p.tokens = append(p.tokens, token{kind: tokPunct, text: s[i : i+2]})
+			i += 2
+		case strings.IndexByte(".[]{}()|,:<>", c) >= 0:
+			p.tokens = append(p.tokens, token{kind: tokPunct, text: s[i : i+1]})
+			i++
+		case c == '"':
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(s) {
+				return fmt.Errorf("unterminated string")
+			}
+
+			var str string
+			if err := json.Unmarshal([]byte(s[i:j+1]), &str); err != nil {
+				return fmt.Errorf("invalid string %s", s[i:j+1])
+			}
+			p.tokens = append(p.tokens, token{kind: tokString, text: s[i : j+1], val: str})
+			i = j + 1
+		case c == '-' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(s) && strings.IndexByte("0123456789.eE+-", s[j]) >= 0 {
+				j++
+			}
+
+			f, err := strconv.ParseFloat(s[i:j], 64)
+			if err != nil {
+				return fmt.Errorf("invalid number %s", s[i:j])
+			}
+			p.tokens = append(p.tokens, token{kind: tokNumber, text: s[i:j], val: f})
+			i = j
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			p.tokens = append(p.tokens, token{kind: tokIdent, text: s[i:j]})
+			i = j
+		default:
+			return fmt.Errorf("unexpected character %q", c)
+		}
+	}
+	return nil
+}
+
+func (p *filterParser) done() bool {
+	return p.i >= len(p.tokens)
+}
+
+func (p *filterParser) peek() token {
+	if p.done() {
+		return token{}
+	}
+	return p.tokens[p.i]
+}
+
+func (p *filterParser) next() token {
+	t := p.peek()
+	p.i++
+	return t
+}
+
+// accept consumes the next token if it is the given punctuation.
+func (p *filterParser) accept(punct string) bool {
+	if t := p.peek(); !p.done() && t.kind == tokPunct && t.text == punct {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) expect(punct string) error {
+	if !p.accept(punct) {
+		if p.done() {
+			return fmt.Errorf("expected %q, got end of expression", punct)
+		}
+		return fmt.Errorf("expected %q, got %q", punct, p.peek().text)
+	}
+	return nil
+}
+
+func (p *filterParser) parsePipe() (filterFunc, error) {
+	f, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("|") {
+		g, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		f = pipe(f, g)
+	}
+	return f, nil
+}
+
+func (p *filterParser) parseTerm() (filterFunc, error) {
+	if p.done() {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	t := p.next()
+	switch {
+	case t.kind == tokPunct && t.text == ".":
+		return p.parsePath()
+	case t.kind == tokPunct && t.text == "{":
+		return p.parseObject()
+	case t.kind == tokPunct && t.text == "(":
+		f, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		return f, p.expect(")")
+	case t.kind == tokIdent && t.text == "select":
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		cond, err := p.parseCond()
+		if err != nil {
+			return nil, err
+		}
+		return selectFilter(cond), p.expect(")")
+	case t.kind == tokString || t.kind == tokNumber:
+		return constant(t.val), nil
+	case t.kind == tokIdent && t.text == "true":
+		return constant(true), nil
+	case t.kind == tokIdent && t.text == "false":
+		return constant(false), nil
+	case t.kind == tokIdent && t.text == "null":
+		return constant(nil), nil
+	default:
+		return nil, fmt.Errorf("unexpected %q", t.text)
+	}
+}
+
+// parsePath parses the rest of a path, after its leading dot.
+func (p *filterParser) parsePath() (filterFunc, error) {
+	f := identity
+
+	// the first segment directly follows the leading dot
+	if t := p.peek(); !p.done() && (t.kind == tokIdent || t.kind == tokString) {
+		f = pipe(f, field(p.next().text, t))
+	}
+
+	for {
+		switch {
+		case p.accept("["):
+			g, err := p.parseBracket()
+			if err != nil {
+				return nil, err
+			}
+			f = pipe(f, g)
+		case !p.done() && p.peek().kind == tokPunct && p.peek().text == "." &&
+			p.i+1 < len(p.tokens) && (p.tokens[p.i+1].kind == tokIdent || p.tokens[p.i+1].kind == tokString):
+			p.next()
+			t := p.next()
+			f = pipe(f, field(t.text, t))
+		default:
+			return f, nil
+		}
+	}
+}
+
+// parseBracket parses the contents of [...], after the opening bracket.
+func (p *filterParser) parseBracket() (filterFunc, error) {
+	if p.accept("]") {
+		return iterate, nil
+	}
+	if p.done() {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	t := p.next()
+	var f filterFunc
+	switch t.kind {
+	case tokString:
+		f = field(t.text, t)
+	case tokNumber:
+		n := t.val.(float64)
+		if n != float64(int(n)) {
+			return nil, fmt.Errorf("invalid index %s", t.text)
+		}
+		f = index(int(n))
+	default:
+		return nil, fmt.Errorf("unexpected %q", t.text)
+	}
+	return f, p.expect("]")
+}
+
+func (p *filterParser) parseObject() (filterFunc, error) {
+	type entry struct {
+		key string
+		f   filterFunc
+	}
+	var entries []entry
+
+	for !p.accept("}") {
+		if len(entries) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+
+		t := p.next()
+		if t.kind != tokIdent && t.kind != tokString {
+			return nil, fmt.Errorf("expected object key, got %q", t.text)
+		}
+		key := t.text
+		if t.kind == tokString {
+			key = t.val.(string)
+		}
+
+		f := field(t.text, t)
+		if p.accept(":") {
+			var err error
+			if f, err = p.parseTerm(); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, entry{key: key, f: f})
+	}
+
+	return func(v any) ([]any, error) {
+		obj := make(map[string]any, len(entries))
+		for _, e := range entries {
+			res, err := e.f(v)
+			if err != nil {
+				return nil, err
+			}
+
+			switch len(res) {
+			case 0:
+				obj[e.key] = nil
+			case 1:
+				obj[e.key] = res[0]
+			default:
+				return nil, fmt.Errorf("value of %q has %d results", e.key, len(res))
+			}
+		}
+		return []any{obj}, nil
+	}, nil
+}
+
+func (p *filterParser) parseCond() (filterFunc, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	switch {
+	case p.done() || t.kind != tokPunct:
+		return left, nil
+	case t.text == "==", t.text == "!=", t.text == "<", t.text == "<=", t.text == ">", t.text == ">=":
+		p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		return compare(t.text, left, right), nil
+	default:
+		return left, nil
+	}
+}
+
+func identity(v any) ([]any, error) {
+	return []any{v}, nil
+}
+
+func constant(c any) filterFunc {
+	return func(any) ([]any, error) {
+		return []any{c}, nil
+	}
+}
+
+func pipe(f, g filterFunc) filterFunc {
+	return func(v any) ([]any, error) {
+		res, err := f(v)
+		if err != nil {
+			return nil, err
+		}
+
+		var out []any
+		for _, r := range res {
+			gres, err := g(r)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, gres...)
+		}
+		return out, nil
+	}
+}
+
+func field(text string, t token) filterFunc {
+	key := text
+	if t.kind == tokString {
+		key = t.val.(string)
+	}
+
+	return func(v any) ([]any, error) {
+		switch v := v.(type) {
+		case nil:
+			return []any{nil}, nil
+		case map[string]any:
+			return []any{v[key]}, nil
+		default:
+			return nil, fmt.Errorf("cannot get field %q of %s", key, typeName(v))
+		}
+	}
+}
+
+func index(i int) filterFunc {
+	return func(v any) ([]any, error) {
+		switch v := v.(type) {
+		case nil:
+			return []any{nil}, nil
+		case []any:
+			// filters may be applied concurrently, so i must not change
+			j := i
+			if j < 0 {
+				j += len(v)
+			}
+			if j < 0 || j >= len(v) {
+				return []any{nil}, nil
+			}
+			return []any{v[j]}, nil
+		default:
+			return nil, fmt.Errorf("cannot index %s", typeName(v))
+		}
+	}
+}
+
+func iterate(v any) ([]any, error) {
+	switch v := v.(type) {
+	case []any:
+		return v, nil
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+
+		out := make([]any, len(keys))
+		for i, k := range keys {
+			out[i] = v[k]
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("cannot iterate over %s", typeName(v))
+	}
+}
+
+func selectFilter(cond filterFunc) filterFunc {
+	return func(v any) ([]any, error) {
+		res, err := cond(v)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range res {
+			if r != nil && r != false {
+				return []any{v}, nil
+			}
+		}
+		return nil, nil
+	}
+}
+
+func compare(op string, left, right filterFunc) filterFunc {
+	return func(v any) ([]any, error) {
+		ls, err := left(v)
+		if err != nil {
+			return nil, err
+		}
+		rs, err := right(v)
+		if err != nil {
+			return nil, err
+		}
+
+		var out []any
+		for _, l := range ls {
+			for _, r := range rs {
+				b, err := compareValues(op, l, r)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, b)
+			}
+		}
+		return out, nil
+	}
+}
+
+func compareValues(op string, l, r any) (bool, error) {
+	var c int
+	lf, lnum := toFloat(l)
+	rf, rnum := toFloat(r)
+	ls, lstr := l.(string)
+	rs, rstr := r.(string)
+
+	switch {
+	case lnum && rnum:
+		switch {
+		case lf < rf:
+			c = -1
+		case lf > rf:
+			c = 1
+		}
+	case lstr && rstr:
+		c = strings.Compare(ls, rs)
+	case op == "==":
+		return reflect.DeepEqual(l, r), nil
+	case op == "!=":
+		return !reflect.DeepEqual(l, r), nil
+	default:
+		return false, fmt.Errorf("cannot compare %s and %s", typeName(l), typeName(r))
+	}
+
+	switch op {
+	case "==":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case int64, uint64, float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
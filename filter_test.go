@@ -0,0 +1,267 @@
+package cmds
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+)
+
+type filterTestObj struct {
+	Name  string
+	Size  int `json:"size"`
+	Links []filterTestLink
+}
+
+type filterTestLink struct {
+	Hash string
+	Size int
+}
+
+func TestFilter(t *testing.T) {
+	v := &filterTestObj{
+		Name: "foo",
+		Size: 42,
+		Links: []filterTestLink{
+			{Hash: "a", Size: 1},
+			{Hash: "b", Size: 20},
+		},
+	}
+
+	testCases := []struct {
+		expr string
+		exp  string // results, JSON encoded and joined
+	}{
+		{".", `{"Links":[{"Hash":"a","Size":1},{"Hash":"b","Size":20}],"Name":"foo","size":42}`},
+		{".Name", `"foo"`},
+		{`."size"`, `42`},
+		{".Missing", `null`},
+		{".Missing.Deeper", `null`},
+		{".Links[1].Hash", `"b"`},
+		{".Links[-1].Size", `20`},
+		{".Links[5]", `null`},
+		{".Links[].Hash", `"a" "b"`},
+		{".Links | .[0] | .Hash", `"a"`},
+		{"{Name, Size: .size}", `{"Name":"foo","Size":42}`},
+		{`{"n": .Name, first: .Links[0].Hash}`, `{"first":"a","n":"foo"}`},
+		{".Links[] | select(.Size > 10) | .Hash", `"b"`},
+		{`.Links[] | select(.Hash != "a") | .Size`, `20`},
+		{"select(.Missing)", ``},
+		{"select(.Name)", `{"Links":[{"Hash":"a","Size":1},{"Hash":"b","Size":20}],"Name":"foo","size":42}`},
+		{"select(.size <= 42) | .Name", `"foo"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			f, err := ParseFilter(tc.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := f.Apply(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var parts [][]byte
+			for _, r := range res {
+				data, err := json.Marshal(r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				parts = append(parts, data)
+			}
+
+			if got := string(bytes.Join(parts, []byte(" "))); got != tc.exp {
+				t.Fatalf("expected %s, got %s", tc.exp, got)
+			}
+		})
+	}
+}
+
+func TestFilterReuse(t *testing.T) {
+	f, err := ParseFilter(".[-1]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		v   []int
+		exp string
+	}{
+		{[]int{1, 2, 3}, "3"},
+		{[]int{1, 2, 3, 4, 5}, "5"},
+		{[]int{7}, "7"},
+		{[]int{}, "<nil>"},
+		{[]int{8, 9}, "9"},
+	} {
+		res, err := f.Apply(tc.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res) != 1 || fmt.Sprint(res[0]) != tc.exp {
+			t.Errorf("%v: expected %v, got %v", tc.v, tc.exp, res)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for n := range 8 {
+		wg.Go(func() {
+			v := make([]int, n+1)
+			v[n] = n
+			for range 100 {
+				if res, err := f.Apply(v); err != nil || fmt.Sprint(res[0]) != fmt.Sprint(n) {
+					t.Errorf("%d: unexpected result %v, %v", n, res, err)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func TestFilterErrors(t *testing.T) {
+	for _, expr := range []string{
+		"Name",
+		".Name |",
+		".Links[",
+		".Links[1.5]",
+		"{Name",
+		"select(.Name",
+		`."unterminated`,
+		".Name $",
+	} {
+		_, err := ParseFilter(expr)
+		if e, ok := err.(Error); !ok || e.Code != ErrClient {
+			t.Errorf("%s: expected client error, got %v", expr, err)
+		}
+	}
+
+	// errors applying the filter
+	for _, expr := range []string{".Name.First", ".Name[0]", ".size[]", "select(.Name > 1)"} {
+		f, err := ParseFilter(expr)
+		if err != nil {
+			t.Fatalf("%s: %s", expr, err)
+		}
+		if _, err := f.Apply(&filterTestObj{Name: "foo"}); err == nil {
+			t.Errorf("%s: expected error", expr)
+		}
+	}
+}
+
+func TestFilterEncoder(t *testing.T) {
+	testCases := []struct {
+		enc EncodingType
+		exp string
+	}{
+		{JSON, "\"a\"\n\"b\"\n"},
+		{Text, "a\nb\n"},
+		{Table, "Value\na\nb\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.enc), func(t *testing.T) {
+			cmd := &Command{
+				Type: filterTestObj{},
+				Encoders: EncoderMap{
+					Text: MakeTypedEncoder(func(req *Request, w io.Writer, v *filterTestObj) error {
+						t.Fatal("the command's encoder should not be used for filtered values")
+						return nil
+					}),
+				},
+			}
+			req := &Request{
+				Command: cmd,
+				Options: OptMap{EncLong: string(tc.enc), FilterOpt: ".Links[].Hash"},
+			}
+
+			buf := new(bytes.Buffer)
+			_, enc, err := GetEncoder(req, buf, JSON)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := enc.Encode(&filterTestObj{Links: []filterTestLink{{Hash: "a"}, {Hash: "b"}}}); err != nil {
+				t.Fatal(err)
+			}
+
+			if buf.String() != tc.exp {
+				t.Fatalf("expected %q, got %q", tc.exp, buf.String())
+			}
+		})
+	}
+
+	req := &Request{Command: &Command{}, Options: OptMap{FilterOpt: "{"}}
+	if _, _, err := GetEncoder(req, new(bytes.Buffer), JSON); err == nil {
+		t.Fatal("expected error for invalid filter")
+	}
+}
+
+func TestFilterTable(t *testing.T) {
+	req := &Request{
+		Command: &Command{Type: filterTestObj{}},
+		Options: OptMap{EncLong: Table, FilterOpt: ".Links[] | {Hash, Big: .Size}"},
+	}
+
+	buf := new(bytes.Buffer)
+	_, enc, err := GetEncoder(req, buf, JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(&filterTestObj{Links: []filterTestLink{{Hash: "a", Size: 1}, {Hash: "b", Size: 20}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := "Big  Hash\n1    a\n20   b\n"
+	if buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+}
+
+func TestFilterCommandOption(t *testing.T) {
+	own := &Command{
+		Options:  []Option{StringOption(FilterOpt, "Only list matching names.")},
+		Encoders: EncoderMap{JSON: Encoders[Text]},
+	}
+	// trees without the global filter may use its name for other options
+	root := &Command{
+		Subcommands: map[string]*Command{
+			"ls":  own,
+			"cat": {Options: []Option{OptionFilter}},
+		},
+	}
+
+	req, err := NewRequest(context.Background(), []string{"ls"}, OptMap{FilterOpt: "foo*"}, nil, nil, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if UsesFilterOption(req) {
+		t.Fatal("expected the option of the command, not the filter")
+	}
+	if f, err := GetFilter(req); f != nil || err != nil {
+		t.Fatalf("expected no filter, got %v, %v", f, err)
+	}
+
+	// the command's encoder is still used
+	buf := new(bytes.Buffer)
+	_, enc, err := GetEncoder(req, buf, JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode("value"); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "value" {
+		t.Fatalf("expected the command's encoder, got %q", buf.String())
+	}
+
+	req, err = NewRequest(context.Background(), []string{"cat"}, OptMap{FilterOpt: ".Name"}, nil, nil, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, err := GetFilter(req); f == nil || err != nil {
+		t.Fatalf("expected the filter, got %v, %v", f, err)
+	}
+}
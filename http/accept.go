@@ -0,0 +1,174 @@
+package http
+
+import (
+	"fmt"
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+const acceptHeader = "Accept"
+
+// clientAccept is the Accept header sent by the client. The client requests
+// JSON, but commands emitting readers respond with their own content type.
+const clientAccept = "application/json, application/octet-stream;q=0.9, text/plain;q=0.9, */*;q=0.1"
+
+// negotiableEncodings are the encodings that can be selected with the Accept
+// header, in order of preference when a media range matches several of them.
+// Tables share their media type with text, so they are only available with
+// the encoding parameter.
+var negotiableEncodings = []cmds.EncodingType{
+	cmds.JSON,
+	cmds.NDJSON,
+	cmds.JSONSeq,
+	cmds.CBOR,
+	cmds.DAGJSON,
+	cmds.YAML,
+	cmds.TOML,
+	cmds.Protobuf,
+	cmds.XML,
+	cmds.Text,
+	cmds.CSV,
+	cmds.EventStream,
+	cmds.OctetStream,
+}
+
+type mediaRange struct {
+	typ, sub string
+	q        float64
+}
+
+func (r mediaRange) specificity() int {
+	switch {
+	case r.typ == "*":
+		return 0
+	case r.sub == "*":
+		return 1
+	default:
+		return 2
+	}
+}
+
+func (r mediaRange) matches(mimeType string) bool {
+	typ, sub, _ := strings.Cut(mimeType, "/")
+	return (r.typ == "*" || r.typ == typ) && (r.sub == "*" || r.sub == sub)
+}
+
+// parseAccept parses the values of Accept headers into media ranges, ordered
+// by preference. Malformed ranges are ignored.
+func parseAccept(values []string) []mediaRange {
+	var ranges []mediaRange
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil {
+				continue
+			}
+
+			typ, sub, ok := strings.Cut(mediaType, "/")
+			if !ok || (typ == "*" && sub != "*") {
+				continue
+			}
+
+			q := 1.0
+			if s, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(s, 64); err != nil || q < 0 || q > 1 {
+					continue
+				}
+			}
+
+			ranges = append(ranges, mediaRange{typ: typ, sub: sub, q: q})
+		}
+	}
+
+	slices.SortStableFunc(ranges, func(a, b mediaRange) int {
+		if a.q != b.q {
+			if a.q > b.q {
+				return -1
+			}
+			return 1
+		}
+		return b.specificity() - a.specificity()
+	})
+	return ranges
+}
+
+// negotiateEncoding picks the encoding for the response to a request with
+// the given Accept header values. The encoding defaults to JSON, e.g. for
+// wildcards and the headers of browsers. It only changes if one of the most
+// preferred media ranges names a media type of an encoding of the command,
+// or if JSON isn't accepted at all. If none of the accepted media types is
+// available, ErrNotAcceptable is returned.
+func negotiateEncoding(accept []string, cmd *cmds.Command) (cmds.EncodingType, error) {
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		return cmds.JSON, nil
+	}
+
+	// media types with q=0 are explicitly refused
+	refused := make(map[string]bool)
+	for _, r := range ranges {
+		if r.q == 0 && r.specificity() == 2 {
+			refused[r.typ+"/"+r.sub] = true
+		}
+	}
+
+	// match returns the first encoding of cmd that r accepts. Wildcards
+	// only match if explicit is unset.
+	match := func(r mediaRange, explicit bool) (cmds.EncodingType, bool) {
+		if r.q == 0 || (explicit && r.specificity() != 2) {
+			return "", false
+		}
+		for _, encType := range negotiableEncodings {
+			mimeType, ok := mimeTypes[encType]
+			if ok && !refused[mimeType] && r.matches(mimeType) && hasEncoder(cmd, encType) {
+				return encType, true
+			}
+		}
+		return "", false
+	}
+
+	for _, r := range ranges {
+		if r.q < ranges[0].q {
+			break
+		}
+		if encType, ok := match(r, true); ok {
+			return encType, nil
+		}
+	}
+
+	if !refused[mimeTypes[cmds.JSON]] {
+		for _, r := range ranges {
+			if r.q > 0 && r.matches(mimeTypes[cmds.JSON]) {
+				return cmds.JSON, nil
+			}
+		}
+	}
+
+	// JSON isn't accepted, so take any other encoding
+	for _, r := range ranges {
+		if encType, ok := match(r, false); ok {
+			return encType, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: no encoding available for %q", ErrNotAcceptable, strings.Join(accept, ", "))
+}
+
+// hasEncoder reports whether values of cmd can be encoded with encType. Like
+// on the command line, text is only available if the command has its own
+// text encoder.
+func hasEncoder(cmd *cmds.Command, encType cmds.EncodingType) bool {
+	if _, ok := cmd.Encoders[encType]; ok {
+		return true
+	}
+	if encType == cmds.Text {
+		return false
+	}
+
+	_, ok := cmds.Encoders[encType]
+	return ok
+}
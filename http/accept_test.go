@@ -0,0 +1,108 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	withText := &cmds.Command{
+		Encoders: cmds.EncoderMap{
+			cmds.Text: cmds.Encoders[cmds.Text],
+		},
+	}
+	withoutText := &cmds.Command{}
+
+	testCases := []struct {
+		accept []string
+		cmd    *cmds.Command
+		exp    cmds.EncodingType
+	}{
+		{nil, withoutText, cmds.JSON},
+		{[]string{"*/*"}, withoutText, cmds.JSON},
+		{[]string{"application/*"}, withoutText, cmds.JSON},
+		{[]string{"application/yaml"}, withoutText, cmds.YAML},
+		{[]string{"application/yaml;q=0.5, application/cbor"}, withoutText, cmds.CBOR},
+		{[]string{"application/cbor;q=0.5", "application/yaml;q=0.8"}, withoutText, cmds.YAML},
+		// wildcards keep the default
+		{[]string{"text/*;q=0.9, application/json;q=0.1"}, withText, cmds.JSON},
+		{[]string{"text/*"}, withText, cmds.Text},
+		{[]string{"text/plain"}, withText, cmds.Text},
+		{[]string{"text/plain, */*;q=0.1"}, withoutText, cmds.JSON},
+		{[]string{"text/csv, */*;q=0.1"}, withoutText, cmds.CSV},
+		{[]string{"application/json;q=0, */*"}, withoutText, cmds.NDJSON},
+		{[]string{"text/html, */*;q=0.8"}, withoutText, cmds.JSON},
+		// browsers get JSON
+		{[]string{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"}, withoutText, cmds.JSON},
+		{[]string{"text/html, application/xml;q=0.9"}, withoutText, cmds.XML},
+		{[]string{"bogus, application/x-ndjson"}, withoutText, cmds.NDJSON},
+		{[]string{"application/toml;q=2, application/cbor"}, withoutText, cmds.CBOR},
+	}
+
+	for _, tc := range testCases {
+		encType, err := negotiateEncoding(tc.accept, tc.cmd)
+		if err != nil {
+			t.Errorf("%q: %s", tc.accept, err)
+			continue
+		}
+		if encType != tc.exp {
+			t.Errorf("%q: expected %s, got %s", tc.accept, tc.exp, encType)
+		}
+	}
+
+	for _, accept := range []string{"text/html", "image/*", "application/json;q=0", "text/plain"} {
+		_, err := negotiateEncoding([]string{accept}, withoutText)
+		if !errors.Is(err, ErrNotAcceptable) {
+			t.Errorf("%q: expected ErrNotAcceptable, got %v", accept, err)
+		}
+	}
+}
+
+func TestAcceptHeader(t *testing.T) {
+	testCases := []struct {
+		path                string
+		accept              string
+		statusCode          int
+		expectedContentType string
+	}{
+		{"/version", "application/yaml", http.StatusOK, "application/yaml"},
+		{"/version", "text/plain", http.StatusOK, "text/plain"},
+		{"/version", "image/png", http.StatusNotAcceptable, ""},
+		// the encoding parameter takes precedence
+		{"/version?encoding=cbor", "application/yaml", http.StatusOK, "application/cbor"},
+		{"/version?encoding=cbor", "image/png", http.StatusOK, "application/cbor"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path+" "+tc.accept, func(t *testing.T) {
+			_, srv := getTestServer(t, nil, true)
+			defer srv.Close()
+
+			req, err := http.NewRequest("POST", srv.URL+tc.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Origin", "http://localhost")
+			req.Header.Set("Accept", tc.accept)
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.statusCode {
+				t.Fatalf("expected status %d, got %d", tc.statusCode, resp.StatusCode)
+			}
+
+			contentType := strings.Split(resp.Header.Get("Content-Type"), ";")[0]
+			if tc.statusCode == http.StatusOK && contentType != tc.expectedContentType {
+				t.Errorf("expected Content-Type %q, got %q", tc.expectedContentType, contentType)
+			}
+		})
+	}
+}
@@ -1,8 +1,12 @@
 package http
 
 import (
+	"cmp"
 	"net/http"
+	"slices"
 	"strings"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
 )
 
 type prefixHandler struct {
@@ -14,8 +18,15 @@ func newPrefixHandler(prefix string, next http.Handler) http.Handler {
 	return prefixHandler{prefix, next}
 }
 
+// matches reports whether the path is the prefix or below it, so that
+// "/api/v1" matches neither "/api/v10/..." nor "/api/v1foo".
+func (h prefixHandler) matches(path string) bool {
+	rest, ok := strings.CutPrefix(path, h.prefix)
+	return ok && (rest == "" || rest[0] == '/' || strings.HasSuffix(h.prefix, "/"))
+}
+
 func (h prefixHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if !strings.HasPrefix(r.URL.Path, h.prefix) {
+	if !h.matches(r.URL.Path) {
 		http.Error(w, ErrNotFound.Error(), http.StatusNotFound)
 		return
 	}
@@ -23,3 +34,40 @@ func (h prefixHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	r.URL.Path = strings.TrimPrefix(r.URL.Path, h.prefix)
 	h.next.ServeHTTP(w, r)
 }
+
+// versionHandler serves every API version under its own prefix. It is
+// sorted by descending prefix length, so the longest matching prefix wins.
+type versionHandler []prefixHandler
+
+func newVersionHandler(env cmds.Environment, root func() *cmds.Command, cfg *ServerConfig) http.Handler {
+	versions := append([]APIVersion{{Path: cfg.APIPath}}, cfg.APIVersions...)
+
+	h := make(versionHandler, 0, len(versions))
+	for _, v := range versions {
+		vRoot := root
+		if v.Root != nil {
+			vRoot = func() *cmds.Command { return v.Root }
+		}
+		h = append(h, prefixHandler{v.Path, &handler{
+			env:     env,
+			root:    vRoot,
+			cfg:     cfg,
+			version: v.Version,
+		}})
+	}
+
+	slices.SortStableFunc(h, func(a, b prefixHandler) int {
+		return cmp.Compare(len(b.prefix), len(a.prefix))
+	})
+	return h
+}
+
+func (h versionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, ph := range h {
+		if ph.matches(r.URL.Path) {
+			ph.ServeHTTP(w, r)
+			return
+		}
+	}
+	http.Error(w, ErrNotFound.Error(), http.StatusNotFound)
+}
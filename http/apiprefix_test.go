@@ -1,10 +1,14 @@
 package http
 
 import (
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
 )
 
 func TestAPIPrefixHandler(t *testing.T) {
@@ -34,6 +38,13 @@ func TestAPIPrefixHandler(t *testing.T) {
 			nextCalled: false,
 			status:     404,
 		},
+		{
+			prefix:     "/api/v0",
+			reqURL:     "/api/v0version",
+			respBody:   "404 page not found\n",
+			nextCalled: false,
+			status:     404,
+		},
 	}
 
 	assert := func(name string, exp, real any) {
@@ -66,3 +77,96 @@ func TestAPIPrefixHandler(t *testing.T) {
 		assert("response body", tc.respBody, w.Body.String())
 	}
 }
+
+func TestAPIVersions(t *testing.T) {
+	emitPin := func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		return re.Emit(req.Options["pin"])
+	}
+	root := &cmds.Command{
+		Subcommands: map[string]*cmds.Command{
+			"old": {Versions: cmds.VersionRange{Until: 1}, Run: emitPin},
+			"new": {Versions: cmds.VersionRange{Since: 1}, Run: emitPin},
+			"add": {
+				Options: []cmds.Option{
+					cmds.WithVersionDefault(cmds.BoolOption("pin", "Pin the content.").WithDefault(true), 1, false),
+				},
+				Run: emitPin,
+			},
+		},
+	}
+	v2 := &cmds.Command{
+		Subcommands: map[string]*cmds.Command{"only": {Run: emitPin}},
+	}
+
+	cfg := NewServerConfig()
+	cfg.SetAllowedMethods("POST")
+	cfg.APIPath = "/api/v0"
+	cfg.APIVersions = []APIVersion{
+		{Version: 1, Path: "/api/v1"},
+		{Version: 2, Path: "/api/v2", Root: v2},
+	}
+	h := NewHandler(nil, root, cfg)
+
+	tcs := []struct {
+		path    string
+		status  int
+		version string
+		body    string
+	}{
+		{"/api/v0/add", 200, "0", "true"},
+		{"/api/v1/add", 200, "1", "false"},
+		{"/api/v1/add?pin=true", 200, "1", "true"},
+		{"/api/v0/old", 200, "0", ""},
+		{"/api/v1/old", 404, "1", ""},
+		{"/api/v0/new", 404, "0", ""},
+		{"/api/v1/new", 200, "1", ""},
+		{"/api/v2/only", 200, "2", ""},
+		{"/api/v2/add", 404, "2", ""},
+		{"/api/v3/add", 404, "", ""},
+		{"/api/v10/add", 404, "", ""},
+		{"/api/v1foo", 404, "", ""},
+	}
+	for _, tc := range tcs {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", tc.path, nil))
+		if w.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d: %s", tc.path, tc.status, w.Code, w.Body)
+		}
+		if got := w.Header().Get(APIVersionHeader); got != tc.version {
+			t.Errorf("%s: expected version %q, got %q", tc.path, tc.version, got)
+		}
+		if !strings.Contains(w.Body.String(), tc.body) {
+			t.Errorf("%s: expected %q in the body, got %s", tc.path, tc.body, w.Body)
+		}
+	}
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	// the client leaves defaults to the server
+	req, err := cmds.NewRequest(t.Context(), []string{"add"}, nil, nil, nil, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := req.FillDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	re, res := cmds.NewChanResponsePair(req)
+	go NewClient(srv.URL, ClientWithAPIPrefix("/api/v1")).Execute(req, re, nil)
+	if v, err := res.Next(); err != nil || v != false {
+		t.Errorf("expected the default of version 1, got %v, %v", v, err)
+	}
+
+	cfg.APIVersions[0].Version = 3
+	srv2 := httptest.NewServer(NewHandler(nil, root, cfg))
+	defer srv2.Close()
+	req, err = cmds.NewRequest(t.Context(), []string{"add"}, nil, nil, nil, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	re, _ = cmds.NewChanResponsePair(req)
+	err = NewClient(srv2.URL, ClientWithAPIPrefix("/api/v1")).Execute(req, re, nil)
+	if !errors.Is(err, ErrAPIVersionMismatch) {
+		t.Errorf("expected a version mismatch, got %v", err)
+	}
+}
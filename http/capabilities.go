@@ -0,0 +1,280 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+// CapabilitiesPath is the path, below the API path, at which handlers with
+// ServerConfig.ServeCapabilities describe their Capabilities.
+const CapabilitiesPath = "/_capabilities"
+
+// ErrNotServed is returned by clients with discovery enabled for commands
+// the server doesn't serve.
+var ErrNotServed = errors.New("command not served by the server")
+
+// Capabilities describes what a server supports. It is derived from the
+// command tree served at the time of the request, the ServerConfig and the
+// Encoders maps.
+type Capabilities struct {
+	// APIVersion is the version serving the capabilities, and APIVersions
+	// all versions served by the handler.
+	APIVersion  int   `json:"apiVersion"`
+	APIVersions []int `json:"apiVersions"`
+
+	// Methods are the HTTP methods commands can be called with.
+	Methods []string `json:"methods"`
+	// Transports are the ways of exchanging data the server supports in
+	// addition to plain request and response bodies: "multipart" file
+	// uploads, "stream" and "chunked" output, and "progress" frames.
+	Transports []string `json:"transports"`
+	// Encodings are the output encodings of all commands.
+	Encodings []cmds.EncodingType `json:"encodings"`
+
+	Commands []CommandCapabilities `json:"commands"`
+}
+
+// CommandCapabilities describes a command of a server. Commands not served
+// in the API version are left out.
+type CommandCapabilities struct {
+	Path    []string `json:"path"`
+	Aliases []string `json:"aliases,omitempty"`
+	// Hidden is set if the command or one of its parents is left out of
+	// help texts. Hidden commands can still be called.
+	Hidden bool `json:"hidden,omitempty"`
+	// Callable is set if the command can be run, rather than only
+	// grouping subcommands.
+	Callable bool `json:"callable,omitempty"`
+	// NoRemote is set if the command or one of its parents can't be
+	// called over HTTP.
+	NoRemote bool `json:"noRemote,omitempty"`
+	NoLocal  bool `json:"noLocal,omitempty"`
+	// Encodings are the encodings the command adds to or overrides in
+	// Capabilities.Encodings.
+	Encodings []cmds.EncodingType  `json:"encodings,omitempty"`
+	Options   []OptionCapabilities `json:"options,omitempty"`
+}
+
+// OptionCapabilities describes an option of a command. Options apply to the
+// subcommands of the command as well. Type is one of the names returned by
+// cmds.OptionSchemaType.
+type OptionCapabilities struct {
+	Names      []string `json:"names"`
+	Type       string   `json:"type"`
+	Deprecated bool     `json:"deprecated,omitempty"`
+}
+
+// Command returns the command at path, which may use aliases.
+func (c *Capabilities) Command(path []string) (*CommandCapabilities, bool) {
+	cmd := c.commandAt(nil)
+	for _, name := range path {
+		if cmd == nil {
+			return nil, false
+		}
+		cmd = c.subcommand(cmd.Path, name)
+	}
+	return cmd, cmd != nil
+}
+
+// Serves reports whether the command at path can be called on the server.
+func (c *Capabilities) Serves(path []string) bool {
+	cmd, ok := c.Command(path)
+	return ok && cmd.Callable && !cmd.NoRemote
+}
+
+func (c *Capabilities) commandAt(path []string) *CommandCapabilities {
+	for i := range c.Commands {
+		if slices.Equal(c.Commands[i].Path, path) {
+			return &c.Commands[i]
+		}
+	}
+	return nil
+}
+
+// subcommand returns the subcommand of the command at parent that is named
+// or aliased name.
+func (c *Capabilities) subcommand(parent []string, name string) *CommandCapabilities {
+	for i := range c.Commands {
+		cmd := &c.Commands[i]
+		if len(cmd.Path) != len(parent)+1 || !slices.Equal(cmd.Path[:len(parent)], parent) {
+			continue
+		}
+		if cmd.Path[len(parent)] == name || slices.Contains(cmd.Aliases, name) {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// capabilitiesCache holds the encoded capabilities of a command tree. The
+// cache is dropped when the handler serves another tree, e.g. after a
+// change of its Registry.
+type capabilitiesCache struct {
+	root *cmds.Command
+	body []byte
+}
+
+// serveCapabilities writes the capabilities of the handler as JSON.
+func (h *handler) serveCapabilities(w http.ResponseWriter, root *cmds.Command) {
+	cached := h.capabilities.Load()
+	if cached == nil || cached.root != root {
+		body, err := json.Marshal(h.describe(root))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		cached = &capabilitiesCache{root: root, body: body}
+		h.capabilities.Store(cached)
+	}
+
+	w.Header().Set(contentTypeHeader, applicationJSON)
+	w.Write(cached.body)
+}
+
+// describe returns the capabilities of the handler serving root.
+func (h *handler) describe(root *cmds.Command) *Capabilities {
+	caps := &Capabilities{
+		APIVersion:  h.version,
+		APIVersions: []int{0},
+		Methods:     []string{http.MethodPost},
+		Transports:  []string{"multipart", "stream", "chunked", "progress"},
+		Encodings:   slices.Sorted(maps.Keys(cmds.Encoders)),
+	}
+	for _, v := range h.cfg.APIVersions {
+		caps.APIVersions = append(caps.APIVersions, v.Version)
+	}
+	slices.Sort(caps.APIVersions)
+	caps.APIVersions = slices.Compact(caps.APIVersions)
+	if h.cfg.AllowGet {
+		caps.Methods = append(caps.Methods, http.MethodGet)
+	}
+
+	var visit func(path []string, cmd *cmds.Command, hidden, noRemote bool)
+	visit = func(path []string, cmd *cmds.Command, hidden, noRemote bool) {
+		if !cmd.Versions.Contains(h.version) {
+			return
+		}
+		hidden = hidden || cmd.Hidden
+		noRemote = noRemote || cmd.NoRemote
+
+		cc := CommandCapabilities{
+			Path:      path,
+			Aliases:   cmd.Aliases,
+			Hidden:    hidden,
+			Callable:  cmd.Run != nil,
+			NoRemote:  noRemote,
+			NoLocal:   cmd.NoLocal,
+			Encodings: slices.Sorted(maps.Keys(cmd.Encoders)),
+		}
+		for _, opt := range cmd.Options {
+			cc.Options = append(cc.Options, OptionCapabilities{
+				Names:      opt.Names(),
+				Type:       cmds.OptionSchemaType(opt.Type()),
+				Deprecated: cmds.OptionDeprecation(opt) != nil,
+			})
+		}
+		caps.Commands = append(caps.Commands, cc)
+
+		for _, name := range slices.Sorted(maps.Keys(cmd.Subcommands)) {
+			visit(append(slices.Clip(path), name), cmd.Subcommands[name], hidden, noRemote)
+		}
+	}
+	visit([]string{}, root, false, false)
+
+	return caps
+}
+
+// ClientWithDiscovery makes the client ask the server for its Capabilities
+// before running the first command. Commands the server doesn't serve are
+// run by the fallback executor, if any, or fail with ErrNotServed without a
+// round trip. Servers without discovery run all commands as usual.
+func ClientWithDiscovery() ClientOpt {
+	return func(c *client) {
+		c.discovery = true
+	}
+}
+
+// Discoverer is implemented by the executors returned by NewClient.
+type Discoverer interface {
+	// Capabilities returns the capabilities of the server. The result is
+	// cached for the lifetime of the client.
+	Capabilities(ctx context.Context) (*Capabilities, error)
+}
+
+// capabilitiesResult is the cached answer of a server to a capabilities
+// request.
+type capabilitiesResult struct {
+	caps *Capabilities
+	err  error
+}
+
+// Capabilities requests the capabilities of the server once, and returns
+// the cached result afterwards. It fails with ErrNotFound if the server
+// doesn't serve them. Failures to reach the server are not cached.
+func (c *client) Capabilities(ctx context.Context) (*Capabilities, error) {
+	if res := c.capabilities.Load(); res != nil {
+		return res.caps, res.err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.serverAddress+c.apiPrefix+CapabilitiesPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set(uaHeader, c.ua)
+	httpReq.Header.Set(acceptHeader, applicationJSON)
+	for key, val := range c.headers {
+		httpReq.Header.Set(key, val)
+	}
+
+	httpRes, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpRes.Body.Close()
+
+	res := new(capabilitiesResult)
+	switch {
+	case httpRes.StatusCode == http.StatusNotFound:
+		res.err = fmt.Errorf("%w: the server doesn't serve its capabilities", ErrNotFound)
+	case httpRes.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("requesting capabilities: %s", httpRes.Status)
+	default:
+		if err := c.checkAPIVersion(httpRes); err != nil {
+			return nil, err
+		}
+		res.caps = new(Capabilities)
+		if err := json.NewDecoder(httpRes.Body).Decode(res.caps); err != nil {
+			return nil, fmt.Errorf("decoding capabilities: %w", err)
+		}
+	}
+
+	c.capabilities.Store(res)
+	return res.caps, res.err
+}
+
+// notServed reports whether discovery is enabled and the server announced
+// that it doesn't serve req.
+func (c *client) notServed(req *cmds.Request) bool {
+	if !c.discovery {
+		return false
+	}
+	caps, err := c.Capabilities(req.Context)
+	if err != nil {
+		// leave it to the request to find out
+		return false
+	}
+	return !caps.Serves(req.Path)
+}
+
+// errNotServed returns the error of commands that aren't served.
+func errNotServed(req *cmds.Request) error {
+	return fmt.Errorf("%w: %s", ErrNotServed, strings.Join(req.Path, " "))
+}
@@ -0,0 +1,189 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+func capabilitiesRoot() *cmds.Command {
+	run := func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		return re.Emit("remote")
+	}
+	return &cmds.Command{
+		Options: []cmds.Option{cmds.StringOption("enc", "The encoding.")},
+		Subcommands: map[string]*cmds.Command{
+			"ls": {
+				Aliases: []string{"list"},
+				Options: []cmds.Option{
+					cmds.DurationOption("timeout", "t", "The timeout."),
+					cmds.WithDeprecation(cmds.BoolOption("old", "Use the old format."), cmds.Deprecation{Removed: true}),
+				},
+				Encoders: cmds.EncoderMap{cmds.Text: cmds.Encoders[cmds.Text]},
+				Run:      run,
+			},
+			"secret": {
+				Hidden:      true,
+				Run:         run,
+				Subcommands: map[string]*cmds.Command{"sub": {Run: run}},
+			},
+			"local": {
+				NoRemote:    true,
+				Run:         run,
+				Subcommands: map[string]*cmds.Command{"sub": {Run: run}},
+			},
+			"group": {
+				Subcommands: map[string]*cmds.Command{"new": {Versions: cmds.VersionRange{Since: 1}, Run: run}},
+			},
+		},
+	}
+}
+
+func TestCapabilitiesEndpoint(t *testing.T) {
+	reg := cmds.NewRegistry(capabilitiesRoot())
+	cfg := NewServerConfig()
+	cfg.SetAllowedMethods("POST")
+	cfg.AllowGet = true
+	h := NewRegistryHandler(nil, reg, cfg)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", CapabilitiesPath, nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected no capabilities by default, got %d", w.Code)
+	}
+
+	cfg.ServeCapabilities = true
+	get := func() *Capabilities {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", CapabilitiesPath, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected response %d: %s", w.Code, w.Body)
+		}
+		var caps Capabilities
+		if err := json.Unmarshal(w.Body.Bytes(), &caps); err != nil {
+			t.Fatal(err)
+		}
+		return &caps
+	}
+
+	caps := get()
+	if !slices.Equal(caps.Methods, []string{"POST", "GET"}) || !slices.Equal(caps.APIVersions, []int{0}) {
+		t.Errorf("unexpected server capabilities %+v", caps)
+	}
+	if !slices.Contains(caps.Encodings, cmds.JSON) {
+		t.Errorf("expected the JSON encoding, got %v", caps.Encodings)
+	}
+
+	ls, ok := caps.Command([]string{"list"})
+	if !ok || !slices.Equal(ls.Path, []string{"ls"}) {
+		t.Fatalf("expected ls by its alias, got %+v", ls)
+	}
+	expOpts := []OptionCapabilities{
+		{Names: []string{"timeout", "t"}, Type: "duration"},
+		{Names: []string{"old"}, Type: "bool", Deprecated: true},
+	}
+	if !slices.EqualFunc(ls.Options, expOpts, func(a, b OptionCapabilities) bool {
+		return slices.Equal(a.Names, b.Names) && a.Type == b.Type && a.Deprecated == b.Deprecated
+	}) {
+		t.Errorf("unexpected options %+v", ls.Options)
+	}
+	if !slices.Equal(ls.Encodings, []cmds.EncodingType{cmds.Text}) {
+		t.Errorf("unexpected encodings %v", ls.Encodings)
+	}
+
+	for path, exp := range map[string]bool{"ls": true, "secret": true, "secret/sub": true, "local": false, "local/sub": false, "group": false, "group/new": false} {
+		if got := caps.Serves(strings.Split(path, "/")); got != exp {
+			t.Errorf("%s: expected served %v, got %v", path, exp, got)
+		}
+	}
+	if sub, ok := caps.Command([]string{"local", "sub"}); !ok || !sub.NoRemote {
+		t.Errorf("expected local/sub to inherit NoRemote, got %+v", sub)
+	}
+	if sub, ok := caps.Command([]string{"secret", "sub"}); !ok || !sub.Hidden {
+		t.Errorf("expected secret/sub to inherit Hidden, got %+v", sub)
+	}
+
+	// changes of the registry invalidate the cached capabilities
+	if err := reg.Register([]string{"added"}, &cmds.Command{Run: capabilitiesRoot().Subcommands["ls"].Run}); err != nil {
+		t.Fatal(err)
+	}
+	if !get().Serves([]string{"added"}) {
+		t.Error("expected the registered command to be served")
+	}
+}
+
+func TestClientDiscovery(t *testing.T) {
+	root := capabilitiesRoot()
+	cfg := NewServerConfig()
+	cfg.SetAllowedMethods("POST")
+	cfg.ServeCapabilities = true
+
+	var discovered atomic.Int32
+	handler := NewHandler(nil, root, cfg)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == CapabilitiesPath {
+			discovered.Add(1)
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	// the local tree knows a command the server doesn't serve
+	local := capabilitiesRoot()
+	local.Subcommands["local"].NoRemote = false
+	local.Subcommands["local"].Run = func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		return re.Emit("fallback")
+	}
+	fallback := cmds.NewExecutor(local)
+
+	run := func(exe cmds.Executor, path ...string) (any, error) {
+		req, err := cmds.NewRequest(t.Context(), path, nil, nil, nil, local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		re, res := cmds.NewChanResponsePair(req)
+		go func() {
+			if err := exe.Execute(req, re, nil); err != nil {
+				re.CloseWithError(err)
+			}
+		}()
+		return res.Next()
+	}
+
+	c := NewClient(srv.URL, ClientWithDiscovery())
+	if v, err := run(c, "ls"); err != nil || v != "remote" {
+		t.Fatalf("expected ls to run remotely, got %v, %v", v, err)
+	}
+	if v, err := run(c, "secret"); err != nil || v != "remote" {
+		t.Fatalf("expected the hidden command to run remotely, got %v, %v", v, err)
+	}
+	if _, err := run(c, "local"); !errors.Is(err, ErrNotServed) {
+		t.Fatalf("expected ErrNotServed, got %v", err)
+	}
+	if n := discovered.Load(); n != 1 {
+		t.Errorf("expected the capabilities to be requested once, got %d", n)
+	}
+
+	c = NewClient(srv.URL, ClientWithDiscovery(), ClientWithFallback(fallback))
+	if v, err := run(c, "local"); err != nil || v != "fallback" {
+		t.Fatalf("expected the fallback to run local, got %v, %v", v, err)
+	}
+
+	// servers without discovery run every command
+	cfg.ServeCapabilities = false
+	c = NewClient(srv.URL, ClientWithDiscovery())
+	if _, err := c.(Discoverer).Capabilities(t.Context()); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if v, err := run(c, "ls"); err != nil || v != "remote" {
+		t.Fatalf("expected ls to run remotely, got %v, %v", v, err)
+	}
+}
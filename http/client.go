@@ -4,12 +4,20 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"maps"
 	"net"
 	"net/http"
 	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
+	cid "github.com/ipfs/go-cid"
 	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/multiformats/go-multiaddr"
 
 	"github.com/ipfs/boxo/files"
 )
@@ -30,6 +38,8 @@ type client struct {
 	headers       map[string]string
 	fallback      cmds.Executor
 	rawAbsPath    bool
+	discovery     bool
+	capabilities  atomic.Pointer[capabilitiesResult]
 }
 
 // ClientOpt is an option that can be passed to the HTTP client constructor.
@@ -60,7 +70,9 @@ func ClientWithHTTPClient(hc *http.Client) ClientOpt {
 	}
 }
 
-// ClientWithAPIPrefix specifies an API URL prefix.
+// ClientWithAPIPrefix specifies an API URL prefix. If the prefix ends in a
+// version such as /api/v1, the client is pinned to that API version, and
+// fails with ErrAPIVersionMismatch if the server reports another one.
 func ClientWithAPIPrefix(apiPrefix string) ClientOpt {
 	return func(c *client) {
 		c.apiPrefix = apiPrefix
@@ -111,6 +123,13 @@ func (c *client) Execute(req *cmds.Request, re cmds.ResponseEmitter, env cmds.En
 		return err
 	}
 
+	if c.notServed(req) {
+		if c.fallback != nil {
+			return c.fallback.Execute(req, re, env)
+		}
+		return errNotServed(req)
+	}
+
 	if cmd.PreRun != nil {
 		err := cmd.PreRun(req, env)
 		if err != nil {
@@ -192,6 +211,8 @@ func (c *client) toHTTPRequest(req *cmds.Request) (*http.Request, error) {
 		httpReq.Header.Set(contentTypeHeader, applicationOctetStream)
 	}
 	httpReq.Header.Set(uaHeader, c.ua)
+	httpReq.Header.Set(acceptHeader, clientAccept)
+	httpReq.Header.Set(acceptProgressHeader, "1")
 
 	for key, val := range c.headers {
 		httpReq.Header.Set(key, val)
@@ -229,6 +250,10 @@ func (c *client) send(req *cmds.Request) (cmds.Response, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := c.checkAPIVersion(httpRes); err != nil {
+		httpRes.Body.Close()
+		return nil, err
+	}
 
 	// parse using the overridden JSON encoding in request
 	res, err := parseResponse(httpRes, req)
@@ -236,6 +261,10 @@ func (c *client) send(req *cmds.Request) (cmds.Response, error) {
 		return nil, err
 	}
 
+	if r, ok := res.(*Response); ok {
+		r.client = c
+	}
+
 	// reset request encoding to what it was before
 	if found && len(previousUserProvidedEncoding) > 0 {
 		// reset to user provided encoding after sending request
@@ -247,11 +276,69 @@ func (c *client) send(req *cmds.Request) (cmds.Response, error) {
 	return res, nil
 }
 
+// isFilledDefault reports whether v is the default FillDefaults set for
+// opt. Defaults aren't sent, so that the server applies the defaults of its
+// API version.
+func isFilledDefault(req *cmds.Request, opt cmds.Option, v any) bool {
+	if opt == nil {
+		return false
+	}
+	src, ok := req.OptionSource(opt.Name())
+	return ok && src.Kind == cmds.SourceDefault && reflect.DeepEqual(v, cmds.OptionVersionDefault(opt, req.APIVersion))
+}
+
+// checkAPIVersion returns ErrAPIVersionMismatch if the server reports
+// another API version than the one in the prefix of the client.
+func (c *client) checkAPIVersion(res *http.Response) error {
+	got := res.Header.Get(APIVersionHeader)
+	want, ok := apiVersionOf(c.apiPrefix)
+	if got == "" || !ok || got == strconv.Itoa(want) {
+		return nil
+	}
+	return fmt.Errorf("%w: the server serves version %s at %s, expected version %d", ErrAPIVersionMismatch, got, c.apiPrefix, want)
+}
+
+// apiVersionOf returns the API version at the end of prefix, e.g. 1 for
+// /api/v1.
+func apiVersionOf(prefix string) (int, bool) {
+	last := prefix[strings.LastIndex(prefix, "/")+1:]
+	digits, ok := strings.CutPrefix(last, "v")
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.Atoi(digits)
+	return v, err == nil && v >= 0
+}
+
+// nextPage requests the page of req following cursor.
+func (c *client) nextPage(req *cmds.Request, cursor string) (*Response, error) {
+	next := *req
+	next.Options = make(cmds.OptMap, len(req.Options))
+	maps.Copy(next.Options, req.Options)
+	next.Options[cmds.CursorOpt] = cursor
+
+	res, err := c.send(&next)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*Response), nil
+}
+
 func getQuery(req *cmds.Request) (string, error) {
 	query := url.Values{}
 
+	var optDefs map[string]cmds.Option
+	if req.Root != nil {
+		optDefs, _ = req.Root.GetOptions(req.Path)
+	}
+
 	for k, v := range req.Options {
-		if OptionSkipMap[k] {
+		if OptionSkipMap[k] || isFilledDefault(req, optDefs[k], v) {
+			continue
+		}
+		// filters are applied by the local response emitter, to the values
+		// decoded into the command's Type
+		if k == cmds.FilterOpt && cmds.UsesFilterOption(req) {
 			continue
 		}
 
@@ -260,9 +347,18 @@ func getQuery(req *cmds.Request) (string, error) {
 			for _, o := range val {
 				query.Add(k, o)
 			}
-		case bool, int, int64, uint, uint64, float64, string:
+		case bool, int, int64, uint, uint64, float64, string,
+			time.Duration, *url.URL, multiaddr.Multiaddr, cid.Cid:
 			str := fmt.Sprintf("%v", v)
 			query.Set(k, str)
+		case cmds.TriBool:
+			if val.IsSet() {
+				query.Set(k, val.String())
+			}
+		case map[string]string:
+			for _, key := range slices.Sorted(maps.Keys(val)) {
+				query.Add(k, key+"="+val[key])
+			}
 		default:
 			return "", fmt.Errorf("unsupported query parameter type. key: %s, value: %v", k, v)
 		}
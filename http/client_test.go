@@ -3,10 +3,15 @@ package http
 import (
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	cid "github.com/ipfs/go-cid"
 	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/multiformats/go-multiaddr"
 )
 
 func TestClientUserAgent(t *testing.T) {
@@ -31,6 +36,10 @@ func TestClientUserAgent(t *testing.T) {
 				t.Errorf("expected user agent %q, got %q", tc.ua, ua)
 			}
 
+			if accept := r.Header.Get("Accept"); accept != clientAccept {
+				t.Errorf("expected accept header %q, got %q", clientAccept, accept)
+			}
+
 			expPath := "/" + strings.Join(tc.path, "/")
 			if path := r.URL.Path; path != expPath {
 				t.Errorf("expected path %q, got %q", expPath, path)
@@ -103,6 +112,7 @@ func TestClientHeader(t *testing.T) {
 	tcs := []testcase{
 		{header: "Authorization", value: "Bearer sdneijfnejvzfregfwe", path: []string{"version"}},
 		{header: "Content-Type", value: "text/plain", path: []string{"version"}},
+		{header: "Accept", value: "application/json", path: []string{"version"}},
 	}
 
 	for _, tc := range tcs {
@@ -135,3 +145,109 @@ func TestClientHeader(t *testing.T) {
 		}
 	}
 }
+
+func TestClientRichOptions(t *testing.T) {
+	u, _ := url.Parse("https://example.com/?a=b")
+	ma, _ := multiaddr.NewMultiaddr("/ip4/127.0.0.1/tcp/5001")
+	c, _ := cid.Decode("bafkqaaa")
+	opts := cmds.OptMap{
+		"duration":  90 * time.Second,
+		"size":      uint64(256 << 10),
+		"url":       u,
+		"multiaddr": ma,
+		"cid":       c,
+		"header":    map[string]string{"a": "1", "b": "2=3"},
+		"quiet":     cmds.False,
+	}
+
+	var ran bool
+	root := &cmds.Command{
+		Subcommands: map[string]*cmds.Command{
+			"rich": {
+				Options: []cmds.Option{
+					cmds.DurationOption("duration"),
+					cmds.ByteSizeOption("size"),
+					cmds.URLOption("url"),
+					cmds.MultiaddrOption("multiaddr"),
+					cmds.CIDOption("cid"),
+					cmds.KeyValueOption("header"),
+					cmds.TristateOption("quiet"),
+				},
+				Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+					ran = true
+					for name, v := range opts {
+						if !reflect.DeepEqual(req.Options[name], v) {
+							t.Errorf("option %s: expected %v, got %#v", name, v, req.Options[name])
+						}
+					}
+					return nil
+				},
+			},
+		},
+	}
+
+	exe := NewLoopbackExecutor(nil, root, nil)
+	defer exe.Close()
+
+	req, err := cmds.NewRequest(t.Context(), []string{"rich"}, opts, nil, nil, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	re, _ := cmds.NewChanResponsePair(req)
+	if err := exe.Execute(req, re, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !ran {
+		t.Fatal("the command was not run")
+	}
+}
+
+func TestClientFilterOption(t *testing.T) {
+	var got any
+	root := &cmds.Command{
+		Subcommands: map[string]*cmds.Command{
+			"ls": {
+				Options: []cmds.Option{cmds.StringOption(cmds.FilterOpt, "Only list matching names.")},
+				Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+					got = req.Options[cmds.FilterOpt]
+					return nil
+				},
+			},
+			"cat": {
+				Options: []cmds.Option{cmds.OptionFilter},
+				Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+					got = req.Options[cmds.FilterOpt]
+					return nil
+				},
+			},
+		},
+	}
+
+	exe := NewLoopbackExecutor(nil, root, nil)
+	defer exe.Close()
+
+	run := func(path, filter string) {
+		t.Helper()
+		got = nil
+		req, err := cmds.NewRequest(t.Context(), []string{path}, cmds.OptMap{cmds.FilterOpt: filter}, nil, nil, root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		re, _ := cmds.NewChanResponsePair(req)
+		if err := exe.Execute(req, re, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// options of commands named like the filter are sent
+	run("ls", "foo*")
+	if got != "foo*" {
+		t.Errorf("expected the option of the command, got %v", got)
+	}
+
+	// filters are applied locally
+	run("cat", ".Name")
+	if got != nil {
+		t.Errorf("expected the filter not to be sent, got %v", got)
+	}
+}
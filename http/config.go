@@ -5,7 +5,9 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"time"
 
+	cmds "github.com/ipfs/go-ipfs-cmds"
 	cors "github.com/rs/cors"
 )
 
@@ -20,6 +22,16 @@ type ServerConfig struct {
 	// Example: host:port/api/v0/add. Here the APIPath is /api/v0
 	APIPath string
 
+	// APIVersions are API versions served in addition to version 0,
+	// which is served under APIPath. Requests are served by the version
+	// with the longest matching path, and responses name the version in
+	// the APIVersionHeader.
+	APIVersions []APIVersion
+
+	// ServeCapabilities makes the handler describe its Capabilities at
+	// CapabilitiesPath.
+	ServeCapabilities bool
+
 	// Headers is an optional map of headers that is written out.
 	Headers map[string][]string
 
@@ -31,6 +43,13 @@ type ServerConfig struct {
 	// websites to include resources from the API but not _read_ them.
 	AllowGet bool
 
+	// EventStreamKeepalive is the interval of the keepalive comments sent
+	// to clients of commands using the event-stream encoding. Browsers'
+	// EventSources send GET requests, so they require AllowGet. If zero,
+	// DefaultEventStreamKeepalive is used; a negative value disables
+	// keepalives.
+	EventStreamKeepalive time.Duration
+
 	// corsOpts is a set of options for CORS headers.
 	corsOpts *cors.Options
 
@@ -38,6 +57,24 @@ type ServerConfig struct {
 	corsOptsRWMutex sync.RWMutex
 }
 
+// APIVersion is an API version served by a handler.
+type APIVersion struct {
+	// Version is the number command version ranges and option version
+	// defaults refer to.
+	Version int
+	// Path is the prefix of the request paths of the version, e.g.
+	// /api/v1.
+	Path string
+	// Root is the command tree of the version. If nil, the tree of the
+	// handler is served. Either way, only the commands whose version
+	// range contains Version are served.
+	Root *cmds.Command
+}
+
+// DefaultEventStreamKeepalive is the default interval of keepalives in
+// event streams.
+const DefaultEventStreamKeepalive = 15 * time.Second
+
 func NewServerConfig() *ServerConfig {
 	cfg := new(ServerConfig)
 	cfg.corsOpts = new(cors.Options)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"runtime"
 	"strings"
@@ -15,6 +16,7 @@ import (
 func TestErrors(t *testing.T) {
 	type testcase struct {
 		opts       cmds.OptMap
+		rawOpts    cmds.OptMap
 		path       []string
 		bodyStr    string
 		status     string
@@ -95,6 +97,21 @@ func TestErrors(t *testing.T) {
 			bodyStr: "invalid encoding: foobar\n",
 		},
 
+		{
+			path:    []string{"single"},
+			opts:    cmds.OptMap{cmds.TimeoutOpt: "1m"},
+			status:  "200 OK",
+			bodyStr: `"some value"` + "\n",
+		},
+
+		{
+			// bad timeout
+			path:    []string{"single"},
+			rawOpts: cmds.OptMap{cmds.TimeoutOpt: "soon"},
+			status:  "400 Bad Request",
+			bodyStr: "time: invalid duration \"soon\" (for option \"-timeout\")\n",
+		},
+
 		{
 			path:    []string{"doubleclose"},
 			status:  "200 OK",
@@ -134,6 +151,8 @@ func TestErrors(t *testing.T) {
 			if err != nil {
 				t.Fatal(err)
 			}
+			// sent as is, to check the validation of the server
+			maps.Copy(req.Options, tc.rawOpts)
 
 			httpReq, err := c.(*client).toHTTPRequest(req)
 			if err != nil {
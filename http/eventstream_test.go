@@ -0,0 +1,102 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+func TestEventStream(t *testing.T) {
+	testCases := []struct {
+		path        string
+		lastEventID string
+		body        string
+	}{
+		{
+			path: "/events",
+			body: "id: 1\ndata: 10\n\nid: 2\ndata: 20\n\nid: 3\ndata: 30\n\n" +
+				"event: error\ndata: {\"Message\":\"stream failed\",\"Code\":0,\"Type\":\"error\"}\n\n",
+		},
+		{
+			path:        "/events",
+			lastEventID: "2",
+			body: "id: 3\ndata: 30\n\n" +
+				"event: error\ndata: {\"Message\":\"stream failed\",\"Code\":0,\"Type\":\"error\"}\n\n",
+		},
+		{
+			// errors before any value are sent as an event, too
+			path: "/error",
+			body: "event: error\ndata: {\"Message\":\"an error occurred\",\"Code\":0,\"Type\":\"error\"}\n\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path+tc.lastEventID, func(t *testing.T) {
+			_, srv := getTestServer(t, nil, true)
+			defer srv.Close()
+
+			req, err := http.NewRequest("GET", srv.URL+tc.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Origin", "http://localhost")
+			req.Header.Set("Accept", "text/event-stream")
+			if tc.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tc.lastEventID)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", resp.StatusCode)
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+				t.Errorf("expected Content-Type text/event-stream, got %q", ct)
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tc.body {
+				t.Errorf("expected body\n%q\ngot\n%q", tc.body, body)
+			}
+		})
+	}
+}
+
+func TestEventStreamKeepalive(t *testing.T) {
+	req, err := cmds.NewRequest(context.Background(), nil, cmds.OptMap{cmds.EncLong: cmds.EventStream}, nil, nil, &cmds.Command{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	re, err := NewResponseEmitter(w, http.MethodPost, req, withKeepalive(5*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := re.Emit("first"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := re.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	body := w.Body.String()
+	if !strings.HasPrefix(body, "id: 1\ndata: \"first\"\n\n: keepalive\n\n") {
+		t.Fatalf("expected keepalives after the first event, got %q", body)
+	}
+}
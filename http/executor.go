@@ -0,0 +1,137 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+// LoopbackExecutor executes commands by sending them to a Handler through an
+// in-memory connection. Commands take the same round trip as between a
+// client and a remote daemon: values are encoded and decoded, NoRemote
+// commands are not found and errors surface the way they do over the
+// network. It is meant for testing commands without listening on a port.
+type LoopbackExecutor struct {
+	cmds.Executor
+
+	client *client
+	srv    *http.Server
+	ln     *pipeListener
+	done   chan struct{}
+}
+
+// NewLoopbackExecutor returns a LoopbackExecutor that serves root with env.
+// If cfg is nil, the Handler uses the default server configuration. opts
+// configure the client, e.g. to set headers. The executor must be closed
+// when done.
+func NewLoopbackExecutor(env cmds.Environment, root *cmds.Command, cfg *ServerConfig, opts ...ClientOpt) *LoopbackExecutor {
+	if cfg == nil {
+		cfg = NewServerConfig()
+	}
+
+	ln := newPipeListener()
+	srv := &http.Server{Handler: NewHandler(env, root, cfg)}
+
+	opts = append([]ClientOpt{
+		ClientWithHTTPClient(&http.Client{
+			Transport: &http.Transport{DialContext: ln.DialContext},
+		}),
+		ClientWithAPIPrefix(cfg.APIPath),
+	}, opts...)
+
+	exe := &LoopbackExecutor{
+		Executor: NewClient(ln.Addr().String(), opts...),
+		srv:      srv,
+		ln:       ln,
+		done:     make(chan struct{}),
+	}
+	exe.client = exe.Executor.(*client)
+
+	go func() {
+		defer close(exe.done)
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Errorf("loopback server: %s", err)
+		}
+	}()
+
+	return exe
+}
+
+// Send sends req to the Handler and returns the response, without running
+// the PreRun and PostRun functions of the command. The response gives
+// access to the HTTP headers and, once all values have been read, the
+// trailers.
+func (exe *LoopbackExecutor) Send(req *cmds.Request) (*Response, error) {
+	res, err := exe.client.send(req)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*Response), nil
+}
+
+// Close shuts down the Handler and closes all connections.
+func (exe *LoopbackExecutor) Close() error {
+	err := exe.srv.Close()
+	<-exe.done
+	return err
+}
+
+// pipeListener is a net.Listener that accepts the connections dialed with
+// DialContext. The connections are net.Pipes, so nothing leaves the process.
+type pipeListener struct {
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{
+		conns:  make(chan net.Conn),
+		closed: make(chan struct{}),
+	}
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *pipeListener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+// DialContext connects to the listener, whatever the address.
+func (l *pipeListener) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	client, server := net.Pipe()
+
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.closed:
+	case <-ctx.Done():
+	}
+
+	client.Close()
+	server.Close()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return nil, &net.OpError{Op: "dial", Net: network, Err: net.ErrClosed}
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "loopback" }
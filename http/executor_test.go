@@ -1 +1,103 @@
 package http
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+func getLoopbackExecutor(t *testing.T) (cmds.Environment, *LoopbackExecutor) {
+	env := newTestEnv(t)
+
+	exe := NewLoopbackExecutor(env, cmdRoot, originCfg(defaultOrigins))
+	t.Cleanup(func() {
+		if err := exe.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	return env, exe
+}
+
+func TestLoopbackExecute(t *testing.T) {
+	env, exe := getLoopbackExecutor(t)
+
+	req, err := cmds.NewRequest(context.Background(), []string{"version"}, nil, nil, nil, cmdRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	re, res := cmds.NewChanResponsePair(req)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- exe.Execute(req, re, env)
+	}()
+
+	v, err := res.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, ok := v.(*VersionOutput)
+	if !ok {
+		t.Fatalf("expected a *VersionOutput, got %T", v)
+	}
+	if out.Version != "0.1.2" || out.Commit != "c0mm17" {
+		t.Fatalf("unexpected version %#v", out)
+	}
+
+	if _, err := res.Next(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoopbackNoRemote(t *testing.T) {
+	env, exe := getLoopbackExecutor(t)
+
+	req, err := cmds.NewRequest(context.Background(), []string{"local"}, nil, nil, nil, cmdRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	re, _ := cmds.NewChanResponsePair(req)
+	err = exe.Execute(req, re, env)
+	e, ok := err.(*cmds.Error)
+	if !ok || e.Code != cmds.ErrClient {
+		t.Fatalf("expected a client error, got %v", err)
+	}
+}
+
+func TestLoopbackHeadersAndTrailers(t *testing.T) {
+	_, exe := getLoopbackExecutor(t)
+
+	req, err := cmds.NewRequest(context.Background(), []string{"lateerror"}, nil, nil, nil, cmdRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := exe.Send(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := res.Header().Get(channelHeader); h != "1" {
+		t.Errorf("expected %s header, got %q", channelHeader, h)
+	}
+
+	v, err := res.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, ok := v.(*string); !ok || *s != "some value" {
+		t.Fatalf("unexpected value %#v", v)
+	}
+
+	if _, err := res.Next(); err == nil || err == io.EOF {
+		t.Fatalf("expected the stream error, got %v", err)
+	}
+	if e := res.Trailer().Get(StreamErrHeader); e != "an error occurred" {
+		t.Fatalf("unexpected %s trailer %q", StreamErrHeader, e)
+	}
+}
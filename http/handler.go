@@ -3,11 +3,13 @@ package http
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"sync"
-	"time"
+	"sync/atomic"
 
 	cmds "github.com/ipfs/go-ipfs-cmds"
 	logging "github.com/ipfs/go-log/v2"
@@ -19,11 +21,25 @@ var log = logging.Logger("cmds/http")
 var (
 	// ErrNotFound is returned when the endpoint does not exist.
 	ErrNotFound = errors.New("404 page not found")
+
+	// ErrNotAcceptable is returned when none of the media types accepted by
+	// the client is available.
+	ErrNotAcceptable = errors.New("406 not acceptable")
+
+	// ErrAPIVersionMismatch is returned by clients when the server serves
+	// another API version than the one the client is pinned to.
+	ErrAPIVersionMismatch = errors.New("API version mismatch")
 )
 
 const (
 	// StreamErrHeader is used as trailer when stream errors happen.
-	StreamErrHeader          = "X-Stream-Error"
+	StreamErrHeader = "X-Stream-Error"
+	// NextCursorHeader holds the cursor of the next page of paginated
+	// responses. It is sent as a trailer if values have been emitted.
+	NextCursorHeader = "X-Next-Cursor"
+	// APIVersionHeader holds the API version that served the request, if
+	// the handler serves several versions.
+	APIVersionHeader         = "X-API-Version"
 	streamHeader             = "X-Stream-Output"
 	channelHeader            = "X-Chunked-Output"
 	extraContentLengthHeader = "X-Content-Length"
@@ -32,6 +48,10 @@ const (
 	contentDispHeader        = "Content-Disposition"
 	transferEncodingHeader   = "Transfer-Encoding"
 	originHeader             = "origin"
+	lastEventIDHeader        = "Last-Event-ID"
+	acceptProgressHeader     = "X-Accept-Progress"
+	warningHeader            = "Warning"
+	deprecationHeader        = "Deprecation"
 
 	applicationJSON        = "application/json"
 	applicationOctetStream = "application/octet-stream"
@@ -53,13 +73,30 @@ func skipAPIHeader(h string) bool {
 
 // the internal handler for the API
 type handler struct {
-	root *cmds.Command
+	// root returns the command tree a request is served from
+	root func() *cmds.Command
 	cfg  *ServerConfig
 	env  cmds.Environment
+
+	// version is the API version served by the handler
+	version int
+
+	capabilities atomic.Pointer[capabilitiesCache]
 }
 
 // NewHandler creates the http.Handler for the given commands.
 func NewHandler(env cmds.Environment, root *cmds.Command, cfg *ServerConfig) http.Handler {
+	return newHandler(env, func() *cmds.Command { return root }, cfg)
+}
+
+// NewRegistryHandler creates the http.Handler for the commands of reg. Every
+// request is served from the tree that was current when it arrived, even if
+// reg changes while the request runs.
+func NewRegistryHandler(env cmds.Environment, reg *cmds.Registry, cfg *ServerConfig) http.Handler {
+	return newHandler(env, reg.Root, cfg)
+}
+
+func newHandler(env cmds.Environment, root func() *cmds.Command, cfg *ServerConfig) http.Handler {
 	if cfg == nil {
 		panic("must provide a valid ServerConfig")
 	}
@@ -74,7 +111,9 @@ func NewHandler(env cmds.Environment, root *cmds.Command, cfg *ServerConfig) htt
 		cfg:  cfg,
 	}
 
-	if cfg.APIPath != "" {
+	if len(cfg.APIVersions) > 0 {
+		h = newVersionHandler(env, root, cfg)
+	} else if cfg.APIPath != "" {
 		h = newPrefixHandler(cfg.APIPath, h) // wrap with path prefix checker and trimmer
 	}
 	h = c.Handler(h) // wrap with CORS handler
@@ -157,11 +196,24 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		r.Body = bw
 	}
 
-	req, err := parseRequest(r, h.root)
+	if len(h.cfg.APIVersions) > 0 {
+		w.Header().Set(APIVersionHeader, strconv.Itoa(h.version))
+	}
+
+	root := h.root()
+	if h.cfg.ServeCapabilities && r.URL.Path == CapabilitiesPath {
+		h.serveCapabilities(w, root)
+		return
+	}
+
+	req, err := parseRequest(r, root, h.version)
 	if err != nil {
 		status := http.StatusBadRequest
-		if err == ErrNotFound {
+		switch {
+		case err == ErrNotFound:
 			status = http.StatusNotFound
+		case errors.Is(err, ErrNotAcceptable):
+			status = http.StatusNotAcceptable
 		}
 
 		http.Error(w, err.Error(), status)
@@ -175,20 +227,35 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// warn about deprecated options and arguments (RFC 7234 warn-code 299)
+	if len(req.Warnings) > 0 {
+		w.Header().Set(deprecationHeader, "true")
+		for _, msg := range req.Warnings {
+			w.Header().Add(warningHeader, fmt.Sprintf("299 - %q", msg))
+		}
+	}
+
 	// Handle the timeout up front.
 	var cancel func()
-	if timeoutStr, ok := req.Options[cmds.TimeoutOpt]; ok {
-		timeout, err := time.ParseDuration(timeoutStr.(string))
-		if err != nil {
-			return
-		}
+	if timeout, ok, err := req.Timeout(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if ok {
 		req.Context, cancel = context.WithTimeout(req.Context, timeout)
 	} else {
 		req.Context, cancel = context.WithCancel(req.Context)
 	}
 	defer cancel()
 
-	re, err = NewResponseEmitter(w, r.Method, req, withRequestBodyEOFChan(bodyEOFChan))
+	keepalive := h.cfg.EventStreamKeepalive
+	if keepalive == 0 {
+		keepalive = DefaultEventStreamKeepalive
+	}
+
+	re, err = NewResponseEmitter(w, r.Method, req,
+		withRequestBodyEOFChan(bodyEOFChan),
+		withKeepalive(keepalive),
+		withProgressFrames(r.Header.Get(acceptProgressHeader) != ""))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -199,7 +266,7 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		defer done()
 	}
 
-	h.root.Call(req, re, h.env)
+	root.Call(req, re, h.env)
 }
 
 func setAllowHeader(w http.ResponseWriter, allowGet bool) {
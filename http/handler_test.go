@@ -7,11 +7,14 @@ import (
 	"io"
 	"net/http/httptest"
 	"runtime"
+	"strconv"
+	"strings"
 
 	"testing"
 
 	"github.com/ipfs/boxo/files"
 	cmds "github.com/ipfs/go-ipfs-cmds"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 type VersionOutput struct {
@@ -61,6 +64,7 @@ var (
 			cmds.OptionEncodingType,
 			cmds.OptionStreamChannels,
 			cmds.OptionTimeout,
+			cmds.OptionFilter,
 		},
 
 		Subcommands: map[string]*cmds.Command{
@@ -69,6 +73,75 @@ var (
 					return errors.New("an error occurred")
 				},
 			},
+			"events": {
+				Options: []cmds.Option{
+					cmds.OptionLastEventID,
+				},
+				Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+					id, _ := req.Options[cmds.LastEventIDOpt].(string)
+					last, _ := strconv.Atoi(id)
+					for i := last + 1; i <= 3; i++ {
+						if err := re.Emit(cmds.Event{ID: strconv.Itoa(i), Value: i * 10}); err != nil {
+							return err
+						}
+					}
+					return errors.New("stream failed")
+				},
+				Type: 0,
+			},
+			"list": {
+				Options: []cmds.Option{
+					cmds.OptionLimit,
+					cmds.OptionCursor,
+				},
+				Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+					p, err := cmds.NewPager(req, re)
+					if err != nil {
+						return err
+					}
+
+					for i := p.Offset(); i < 5; i++ {
+						if err := p.Emit(i); err != nil {
+							if err == cmds.ErrPageFull {
+								break
+							}
+							return err
+						}
+					}
+					return p.Finish()
+				},
+				Type: 0,
+			},
+			"progress": {
+				Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+					for i := 1; i <= 2; i++ {
+						if err := cmds.EmitProgress(re, cmds.Progress{Done: uint64(i), Total: 2}); err != nil {
+							return err
+						}
+						if err := re.Emit(i); err != nil {
+							return err
+						}
+					}
+					return nil
+				},
+				Type: 0,
+			},
+			"progressreader": {
+				Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+					if err := cmds.EmitProgress(re, cmds.Progress{Done: 1}); err != nil {
+						return err
+					}
+					return re.Emit(strings.NewReader("raw bytes"))
+				},
+			},
+			"progresserror": {
+				Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+					if err := cmds.EmitProgress(re, cmds.Progress{Done: 1}); err != nil {
+						return err
+					}
+					return errors.New("an error occurred")
+				},
+			},
 			"lateerror": {
 				Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 					re.Emit("some value")
@@ -129,6 +202,15 @@ var (
 					}),
 				},
 			},
+			"protobuf": {
+				Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+					if err := re.Emit(wrapperspb.String("hello")); err != nil {
+						return err
+					}
+					return re.Emit(wrapperspb.String("world"))
+				},
+				Type: &wrapperspb.StringValue{},
+			},
 			"doubleclose": {
 				Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 					t, ok := getTestingT(env)
@@ -288,6 +370,14 @@ var (
 				},
 			},
 
+			"local": {
+				NoRemote: true,
+				Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+					return re.Emit("local")
+				},
+				Type: "",
+			},
+
 			"panic": {
 				Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 					panic("Invalid memory address or nil pointer dereference")
@@ -319,18 +409,22 @@ var (
 	}
 )
 
-func getTestServer(t *testing.T, origins []string, allowGet bool) (cmds.Environment, *httptest.Server) {
-	if len(origins) == 0 {
-		origins = defaultOrigins
-	}
-
-	env := testEnv{
+func newTestEnv(t *testing.T) testEnv {
+	return testEnv{
 		version:     "0.1.2",
 		commit:      "c0mm17", // yes, I know there's no 'm' in hex.
 		repoVersion: "4",
 		t:           t,
 		wait:        make(chan struct{}),
 	}
+}
+
+func getTestServer(t *testing.T, origins []string, allowGet bool) (cmds.Environment, *httptest.Server) {
+	if len(origins) == 0 {
+		origins = defaultOrigins
+	}
+
+	env := newTestEnv(t)
 
 	srvCfg := originCfg(origins)
 	srvCfg.AllowGet = allowGet
@@ -349,3 +443,66 @@ func errEq(err1, err2 error) bool {
 
 	return err1.Error() == err2.Error()
 }
+
+func TestRegistryHandler(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	reg := cmds.NewRegistry(&cmds.Command{
+		Subcommands: map[string]*cmds.Command{
+			"slow": {
+				Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+					close(started)
+					<-release
+					return re.Emit("old")
+				},
+			},
+		},
+	})
+
+	cfg := NewServerConfig()
+	cfg.SetAllowedMethods("POST")
+	h := NewRegistryHandler(nil, reg, cfg)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/new", nil))
+	if w.Code != 404 {
+		t.Fatalf("expected 404 before registering, got %d", w.Code)
+	}
+
+	err := reg.Register([]string{"new"}, &cmds.Command{
+		Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+			return re.Emit("new")
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/new", nil))
+	if w.Code != 200 || !bytes.Contains(w.Body.Bytes(), []byte("new")) {
+		t.Fatalf("unexpected response %d: %s", w.Code, w.Body)
+	}
+
+	// a request in flight keeps its command
+	slow := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.ServeHTTP(slow, httptest.NewRequest("POST", "/slow", nil))
+	}()
+	<-started
+	if err := reg.Unregister([]string{"slow"}); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	<-done
+	if slow.Code != 200 || !bytes.Contains(slow.Body.Bytes(), []byte("old")) {
+		t.Fatalf("unexpected response %d: %s", slow.Code, slow.Body)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/slow", nil))
+	if w.Code != 404 {
+		t.Fatalf("expected 404 after unregistering, got %d", w.Code)
+	}
+}
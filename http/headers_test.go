@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 
 	cmds "github.com/ipfs/go-ipfs-cmds"
@@ -111,3 +112,51 @@ func TestLocalRequestHeadersNil(t *testing.T) {
 		t.Fatalf("Next: %v", err)
 	}
 }
+
+// TestDeprecationHeaders checks that the handler reports deprecated options
+// in Warning and Deprecation headers, and rejects removed ones.
+func TestDeprecationHeaders(t *testing.T) {
+	root := &cmds.Command{
+		Subcommands: map[string]*cmds.Command{
+			"cmd": {
+				Options: []cmds.Option{
+					cmds.StringOption("cid-base", "The multibase of CIDs."),
+					cmds.WithDeprecation(cmds.StringOption("base", "The multibase of CIDs."), cmds.Deprecation{ReplacedBy: "cid-base"}),
+					cmds.WithDeprecation(cmds.BoolOption("old", "Use the old format."), cmds.Deprecation{Removed: true}),
+				},
+				Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+					return re.Emit(req.Options["cid-base"])
+				},
+			},
+		},
+	}
+
+	cfg := NewServerConfig()
+	cfg.SetAllowedMethods("POST")
+	h := NewHandler(nil, root, cfg)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/cmd?base=base32", nil))
+	if w.Code != 200 || !strings.Contains(w.Body.String(), "base32") {
+		t.Fatalf("unexpected response %d: %s", w.Code, w.Body)
+	}
+	if got := w.Header().Get("Deprecation"); got != "true" {
+		t.Errorf("expected a Deprecation header, got %q", got)
+	}
+	exp := `299 - "option \"base\" is deprecated, use \"cid-base\" instead"`
+	if got := w.Header().Values("Warning"); !reflect.DeepEqual(got, []string{exp}) {
+		t.Errorf("expected Warning header %q, got %q", exp, got)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/cmd?cid-base=base32", nil))
+	if got := w.Header().Get("Deprecation"); got != "" {
+		t.Errorf("unexpected Deprecation header %q", got)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/cmd?old=true", nil))
+	if w.Code != 400 || !strings.Contains(w.Body.String(), `option "old" was removed`) {
+		t.Errorf("expected a bad request, got %d: %s", w.Code, w.Body)
+	}
+}
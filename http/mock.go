@@ -0,0 +1,118 @@
+package http
+
+import (
+	"net/http"
+	"reflect"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+// ExamplesKey is the key of the example values of a command in its Extra,
+// which the mock handler responds with. The value is either a []any of the
+// values to emit in order, or a single value that is emitted once:
+//
+//	cmd.Extra = cmd.Extra.SetValue(http.ExamplesKey{}, []any{&Obj{Hash: "Qm..."}})
+type ExamplesKey struct{}
+
+// maxExampleDepth bounds the nesting of generated example values, so that
+// recursive types terminate.
+const maxExampleDepth = 8
+
+// NewMockHandler returns a handler that serves the commands of root like
+// NewHandler does, including the parsing and validation of options and
+// arguments, but responds with example values instead of running the
+// commands. The examples are those registered in the Extra of a command
+// under ExamplesKey, or else a value generated from the command's Type.
+func NewMockHandler(root *cmds.Command, cfg *ServerConfig) http.Handler {
+	return NewHandler(nil, mockCommand(root), cfg)
+}
+
+// mockCommand returns a copy of cmd and its subcommands that emit examples.
+func mockCommand(cmd *cmds.Command) *cmds.Command {
+	mock := *cmd
+	mock.PreRun = nil
+	mock.PostRun = nil
+
+	if cmd.Run != nil {
+		examples := commandExamples(cmd)
+		mock.Run = func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+			// a single value is sent like commands that emit once do, so
+			// clients see the same framing as from the real command
+			if len(examples) == 1 {
+				return cmds.EmitOnce(re, examples[0])
+			}
+			for _, v := range examples {
+				if err := re.Emit(v); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	if cmd.Subcommands != nil {
+		mock.Subcommands = make(map[string]*cmds.Command, len(cmd.Subcommands))
+		for name, sub := range cmd.Subcommands {
+			mock.Subcommands[name] = mockCommand(sub)
+		}
+	}
+
+	return &mock
+}
+
+func commandExamples(cmd *cmds.Command) []any {
+	if v, ok := cmd.Extra.GetValue(ExamplesKey{}); ok {
+		if vs, ok := v.([]any); ok {
+			return vs
+		}
+		return []any{v}
+	}
+
+	if cmd.Type == nil {
+		return nil
+	}
+	return []any{exampleValue(reflect.TypeOf(cmd.Type), 0).Interface()}
+}
+
+// exampleValue returns a value of type t with all exported fields, slices
+// and maps filled in, so that clients see the shape of the output.
+func exampleValue(t reflect.Type, depth int) reflect.Value {
+	v := reflect.New(t).Elem()
+	if depth >= maxExampleDepth {
+		return v
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString("string")
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v.SetUint(1)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1.5)
+	case reflect.Pointer:
+		elem := reflect.New(t.Elem())
+		elem.Elem().Set(exampleValue(t.Elem(), depth+1))
+		v.Set(elem)
+	case reflect.Slice:
+		v.Set(reflect.Append(reflect.MakeSlice(t, 0, 1), exampleValue(t.Elem(), depth+1)))
+	case reflect.Array:
+		for i := range v.Len() {
+			v.Index(i).Set(exampleValue(t.Elem(), depth+1))
+		}
+	case reflect.Map:
+		v.Set(reflect.MakeMapWithSize(t, 1))
+		v.SetMapIndex(exampleValue(t.Key(), depth+1), exampleValue(t.Elem(), depth+1))
+	case reflect.Struct:
+		for i := range t.NumField() {
+			if f := t.Field(i); f.IsExported() {
+				v.Field(i).Set(exampleValue(f.Type, depth+1))
+			}
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,111 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+type mockTree struct {
+	Name     string
+	Size     uint64
+	Children []*mockTree
+	Meta     map[string]bool
+}
+
+func TestMockHandler(t *testing.T) {
+	root := &cmds.Command{
+		Subcommands: map[string]*cmds.Command{
+			"tree": {
+				Arguments: []cmds.Argument{
+					cmds.StringArg("path", true, false, "a path"),
+				},
+				Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+					t.Error("the command should not be run")
+					return nil
+				},
+				Type: mockTree{},
+			},
+			"fixture": {
+				Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+					t.Error("the command should not be run")
+					return nil
+				},
+				Type:  "",
+				Extra: new(cmds.Extra).SetValue(ExamplesKey{}, []any{"one", "two"}),
+			},
+			"local": {
+				NoRemote: true,
+				Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+					return nil
+				},
+			},
+		},
+	}
+
+	srv := httptest.NewServer(NewMockHandler(root, originCfg(defaultOrigins)))
+	defer srv.Close()
+	c := NewClient(srv.URL)
+
+	var header http.Header
+	run := func(path []string, args ...string) ([]any, error) {
+		req, err := cmds.NewRequest(t.Context(), path, nil, args, nil, root)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := c.(*client).send(req)
+		if err != nil {
+			return nil, err
+		}
+		header = res.(*Response).res.Header
+
+		var vs []any
+		for v, err := range res.(*Response).All() {
+			if err != nil {
+				return vs, err
+			}
+			vs = append(vs, v)
+		}
+		return vs, nil
+	}
+
+	vs, err := run([]string{"tree"}, "/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vs) != 1 {
+		t.Fatalf("expected one value, got %v", vs)
+	}
+	// single values aren't streamed
+	if header.Get(channelHeader) != "" {
+		t.Error("expected a single value response")
+	}
+	tree := vs[0].(*mockTree)
+	if tree.Name != "string" || tree.Size != 1 || len(tree.Children) != 1 || !tree.Meta["string"] {
+		t.Fatalf("unexpected example %#v", tree)
+	}
+
+	vs, err = run([]string{"fixture"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vs) != 2 || *(vs[0].(*string)) != "one" || *(vs[1].(*string)) != "two" {
+		t.Fatalf("unexpected fixtures %v", vs)
+	}
+	if header.Get(channelHeader) == "" {
+		t.Error("expected a channel response")
+	}
+
+	// arguments are still validated
+	if _, err := run([]string{"tree"}); err == nil {
+		t.Fatal("expected an error for the missing argument")
+	}
+
+	if _, err := run([]string{"local"}); !reflect.DeepEqual(err, &cmds.Error{Message: "Command not found.", Code: cmds.ErrClient}) {
+		t.Fatalf("expected command not found, got %v", err)
+	}
+}
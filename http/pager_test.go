@@ -0,0 +1,61 @@
+package http
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+func TestClientPages(t *testing.T) {
+	_, srv := getTestServer(t, nil, true)
+	defer srv.Close()
+	c := NewClient(srv.URL)
+
+	req, err := cmds.NewRequest(context.Background(), []string{"list"}, cmds.OptMap{cmds.LimitOpt: 2}, nil, nil, cmdRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := c.(*client).send(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Next only returns the first page
+	var page []any
+	for {
+		v, err := res.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		page = append(page, *(v.(*int)))
+	}
+	if !reflect.DeepEqual(page, []any{0, 1}) {
+		t.Fatalf("unexpected first page %v", page)
+	}
+	if res.(*Response).NextCursor() == "" {
+		t.Fatal("expected a next cursor")
+	}
+
+	res, err = c.(*client).send(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var all []any
+	for v, err := range res.(*Response).All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, *(v.(*int)))
+	}
+	if !reflect.DeepEqual(all, []any{0, 1, 2, 3, 4}) {
+		t.Fatalf("unexpected values %v", all)
+	}
+}
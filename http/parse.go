@@ -12,8 +12,9 @@ import (
 	cmds "github.com/ipfs/go-ipfs-cmds"
 )
 
-// parseRequest parses the data in a http.Request and returns a command Request object
-func parseRequest(r *http.Request, root *cmds.Command) (*cmds.Request, error) {
+// parseRequest parses the data in a http.Request to the given API version
+// and returns a command Request object
+func parseRequest(r *http.Request, root *cmds.Command, version int) (*cmds.Request, error) {
 	if r.URL.Path[0] == '/' {
 		r.URL.Path = r.URL.Path[1:]
 	}
@@ -37,7 +38,7 @@ func parseRequest(r *http.Request, root *cmds.Command) (*cmds.Request, error) {
 	}
 
 	cmd := cmdPath[len(cmdPath)-1]
-	sub := cmd.Subcommands[pth[len(pth)-1]]
+	_, sub := cmd.Subcommand(pth[len(pth)-1])
 
 	if sub == nil {
 		if cmd.Run == nil {
@@ -56,6 +57,12 @@ func parseRequest(r *http.Request, root *cmds.Command) (*cmds.Request, error) {
 		return nil, ErrNotFound
 	}
 
+	// resolve aliases
+	pth, err = root.CanonicalPath(pth)
+	if err != nil || !root.InVersion(pth, version) {
+		return nil, ErrNotFound
+	}
+
 	opts := make(map[string]any)
 	optDefs, err := root.GetOptions(pth)
 	if err != nil {
@@ -78,9 +85,10 @@ func parseRequest(r *http.Request, root *cmds.Command) (*cmds.Request, error) {
 			opts[name] = v
 
 			switch optType := optDef.Type(); optType {
-			case cmds.Strings:
+			case cmds.Strings, cmds.KeyValue:
 				opts[name] = v
-			case cmds.Bool, cmds.Int, cmds.Int64, cmds.Uint, cmds.Uint64, cmds.Float, cmds.String:
+			case cmds.Bool, cmds.Int, cmds.Int64, cmds.Uint, cmds.Uint64, cmds.Float, cmds.String,
+				cmds.Duration, cmds.ByteSize, cmds.URL, cmds.Multiaddr, cmds.CID, cmds.Tristate:
 				if len(v) > 1 {
 					return nil, fmt.Errorf("expected key %s to have only a single value, received %v", name, v)
 				}
@@ -90,9 +98,24 @@ func parseRequest(r *http.Request, root *cmds.Command) (*cmds.Request, error) {
 			}
 		}
 	}
-	// default to setting encoding to JSON
+	// EventSources send the ID of the last event they received when they
+	// reconnect. Pass it on if the command supports resuming.
+	if id := r.Header.Get(lastEventIDHeader); id != "" {
+		if optDef, ok := optDefs[cmds.LastEventIDOpt]; ok {
+			if _, set := opts[optDef.Name()]; !set {
+				opts[optDef.Name()] = id
+			}
+		}
+	}
+
+	// the encoding option takes precedence over the Accept header, which
+	// defaults to JSON
 	if _, ok := opts[cmds.EncLong]; !ok {
-		opts[cmds.EncLong] = cmds.JSON
+		encType, err := negotiateEncoding(r.Header.Values(acceptHeader), cmd)
+		if err != nil {
+			return nil, err
+		}
+		opts[cmds.EncLong] = string(encType)
 	}
 
 	// count required argument definitions
@@ -172,13 +195,24 @@ func parseRequest(r *http.Request, root *cmds.Command) (*cmds.Request, error) {
 	// custom middleware. Clone so handler writes to req.Headers do not
 	// leak back into r.Header.
 	req.Headers = r.Header.Clone()
+	req.APIVersion = version
 
 	err = cmd.CheckArguments(req)
 	if err != nil {
 		return nil, err
 	}
 
-	err = req.FillDefaults()
+	// remote callers must not make the server read its files or use
+	// its environment
+	delete(req.Options, cmds.ConfigFileOpt)
+	if err := req.FillDefaults(); err != nil {
+		return nil, err
+	}
+	if err := req.CheckOptions(); err != nil {
+		return nil, err
+	}
+
+	err = req.CheckDeprecations()
 	return req, err
 }
 
@@ -207,7 +241,7 @@ func parseResponse(httpRes *http.Response, req *cmds.Request) (cmds.Response, er
 		makeDec, ok := cmds.Decoders[encType]
 		if ok {
 			res.dec = makeDec(res.rr)
-		} else if encType != cmds.Text && encType != cmds.OctetStream {
+		} else if encType != cmds.Text && encType != cmds.CSV && encType != cmds.OctetStream {
 			log.Errorf("could not find decoder for encoding %q", encType)
 		} // else we have an io.Reader, which is okay
 	} else {
@@ -6,6 +6,8 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -33,7 +35,7 @@ func TestParse(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	req, err := parseRequest(r, root)
+	req, err := parseRequest(r, root, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -47,12 +49,42 @@ func TestParse(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	_, err = parseRequest(r, root)
+	_, err = parseRequest(r, root, 0)
 	if err != ErrNotFound {
 		t.Errorf("expected ErrNotFound, got: %v", err)
 	}
 }
 
+func TestParseAlias(t *testing.T) {
+	root := &cmds.Command{
+		Subcommands: map[string]*cmds.Command{
+			"block": {
+				Aliases: []string{"blk"},
+				Subcommands: map[string]*cmds.Command{
+					"put": {
+						Aliases: []string{"add"},
+						Run: func(req *cmds.Request, resp cmds.ResponseEmitter, env cmds.Environment) error {
+							return resp.Emit("done")
+						},
+					},
+				},
+			},
+		},
+	}
+
+	r, err := http.NewRequest("GET", "/blk/add", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := parseRequest(r, root, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{"block", "put"}; !reflect.DeepEqual(req.Path, exp) {
+		t.Errorf("incorrect path %v, expected %v", req.Path, exp)
+	}
+}
+
 type parseReqTestCase struct {
 	path string
 	opts url.Values
@@ -78,7 +110,7 @@ func (tc parseReqTestCase) test(t *testing.T) {
 	}
 	httpReq.URL.RawQuery = vs.Encode()
 
-	req, err := parseRequest(httpReq, cmdRoot)
+	req, err := parseRequest(httpReq, cmdRoot, 0)
 	if !errEq(err, tc.err) {
 		t.Fatalf("expected error to be %v, but got %v", tc.err, err)
 	}
@@ -198,6 +230,30 @@ func TestParseResponse(t *testing.T) {
 				},
 			},
 		},
+		{
+			status: 200,
+			header: http.Header{
+				contentTypeHeader: []string{"application/json-seq"},
+				channelHeader:     []string{"1"},
+			},
+			body: mkbuf("\x1e{\"Version\":\"0.1.2\"}\n\x1e{\"Version\":\"0.1.3\"}\n"),
+			values: []any{
+				&VersionOutput{Version: "0.1.2"},
+				&VersionOutput{Version: "0.1.3"},
+			},
+		},
+		{
+			status: 200,
+			header: http.Header{
+				contentTypeHeader: []string{"application/x-ndjson"},
+				channelHeader:     []string{"1"},
+			},
+			body: mkbuf("{\"Version\":\"0.1.2\"}\n{\"Version\":\"0.1.3\"}\n"),
+			values: []any{
+				&VersionOutput{Version: "0.1.2"},
+				&VersionOutput{Version: "0.1.3"},
+			},
+		},
 		{
 			status: 500,
 			header: http.Header{
@@ -213,3 +269,72 @@ func TestParseResponse(t *testing.T) {
 		tc.test(t)
 	}
 }
+
+func TestParseIgnoresServerSources(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "secret.json")
+	if err := os.WriteFile(secret, []byte(`{"name": "mallory"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_NAME", "eve")
+
+	root := &cmds.Command{
+		Options: []cmds.Option{cmds.OptionConfigFile},
+		Subcommands: map[string]*cmds.Command{
+			"greet": {
+				Options: []cmds.Option{
+					cmds.WithEnv(cmds.StringOption("name", "The name."), "TEST_NAME").WithDefault("bob"),
+				},
+				Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+					return re.Emit(req.Options["name"])
+				},
+			},
+		},
+	}
+
+	for _, query := range []string{"", "?config-file=" + url.QueryEscape(secret), "?config-file=/nonexistent.json"} {
+		r, err := http.NewRequest("POST", "/greet"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := parseRequest(r, root, 0)
+		if err != nil {
+			t.Fatalf("%q: %v", query, err)
+		}
+		if name := req.Options["name"]; name != "bob" {
+			t.Errorf("%q: expected the default, got %v", query, name)
+		}
+		if _, ok := req.Options[cmds.ConfigFileOpt]; ok {
+			t.Errorf("%q: expected the config file option to be dropped", query)
+		}
+	}
+}
+
+func TestParseChecksOptions(t *testing.T) {
+	root := &cmds.Command{
+		Subcommands: map[string]*cmds.Command{
+			"cmd": {
+				Options: []cmds.Option{
+					cmds.WithRequired(cmds.StringOption("key", "The key.")),
+					cmds.WithMax(cmds.IntOption("count", "The count."), 3),
+				},
+				Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error { return nil },
+			},
+		},
+	}
+
+	for query, exp := range map[string]string{
+		"?key=k":         "",
+		"":               `option "key" is required`,
+		"?key=k&count=4": `invalid value 4 for option "count": must be at most 3`,
+	} {
+		r, err := http.NewRequest("POST", "/cmd"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = parseRequest(r, root, 0)
+		if exp == "" && err != nil || exp != "" && (err == nil || err.Error() != exp) {
+			t.Errorf("%q: expected error %q, got %v", query, exp, err)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+func TestClientProgress(t *testing.T) {
+	_, srv := getTestServer(t, nil, true)
+	defer srv.Close()
+	c := NewClient(srv.URL)
+
+	req, err := cmds.NewRequest(context.Background(), []string{"progress"}, nil, nil, nil, cmdRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := c.(*client).send(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reports []cmds.Progress
+	res.(cmds.ProgressResponse).SetProgressHandler(func(p cmds.Progress) {
+		reports = append(reports, p)
+	})
+
+	var values []any
+	for {
+		v, err := res.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, *(v.(*int)))
+	}
+
+	if !reflect.DeepEqual(values, []any{1, 2}) {
+		t.Fatalf("unexpected values %v", values)
+	}
+	exp := []cmds.Progress{{Done: 1, Total: 2}, {Done: 2, Total: 2}}
+	if !reflect.DeepEqual(reports, exp) {
+		t.Fatalf("expected progress %v, got %v", exp, reports)
+	}
+}
+
+func TestProgressFrames(t *testing.T) {
+	testCases := []struct {
+		name   string
+		path   string
+		accept string
+		frames bool
+		status int
+		stream bool
+		body   string
+	}{
+		{
+			// clients that don't ask for progress only get values
+			name: "plain json",
+			body: "1\n2\n",
+		},
+		{
+			name:   "json frames",
+			frames: true,
+			body:   "{\"$progress\":{\"Done\":1,\"Total\":2}}\n1\n{\"$progress\":{\"Done\":2,\"Total\":2}}\n2\n",
+		},
+		{
+			name:   "event stream",
+			accept: "text/event-stream",
+			body: "event: progress\ndata: {\"Done\":1,\"Total\":2}\n\nid: 1\ndata: 1\n\n" +
+				"event: progress\ndata: {\"Done\":2,\"Total\":2}\n\nid: 2\ndata: 2\n\n",
+		},
+		{
+			// progress doesn't decide the framing of the response
+			name:   "stream after progress",
+			path:   "/progressreader",
+			frames: true,
+			stream: true,
+			body:   "raw bytes",
+		},
+		{
+			name:   "error after progress",
+			path:   "/progresserror",
+			frames: true,
+			status: http.StatusInternalServerError,
+			body:   "{\"Message\":\"an error occurred\",\"Code\":0,\"Type\":\"error\"}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, srv := getTestServer(t, nil, true)
+			defer srv.Close()
+
+			path := tc.path
+			if path == "" {
+				path = "/progress"
+			}
+			req, err := http.NewRequest("POST", srv.URL+path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Origin", "http://localhost")
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			if tc.frames {
+				req.Header.Set(acceptProgressHeader, "1")
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			status := tc.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if resp.StatusCode != status {
+				t.Errorf("expected status %d, got %d", status, resp.StatusCode)
+			}
+			if stream := resp.Header.Get(streamHeader) != ""; stream != tc.stream {
+				t.Errorf("expected stream %v, got %v", tc.stream, stream)
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tc.body {
+				t.Errorf("expected body\n%q\ngot\n%q", tc.body, body)
+			}
+		})
+	}
+}
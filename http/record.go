@@ -0,0 +1,228 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Interaction is a request to the API and the response it got, as recorded
+// in a cassette. A cassette is a stream of interactions encoded as JSON, one
+// per line.
+type Interaction struct {
+	// Path is the URL path of the request, including the API prefix.
+	Path string `json:"path"`
+
+	// Options are the options of the request, as sent in the query.
+	Options url.Values `json:"options,omitempty"`
+
+	// Arguments are the string arguments of the request.
+	Arguments []string `json:"arguments,omitempty"`
+
+	// Files lists the files sent in the request body. Their contents are
+	// not recorded.
+	Files []FileEntry `json:"files,omitempty"`
+
+	Response RecordedResponse `json:"response"`
+}
+
+// FileEntry describes a file sent in the body of a request.
+type FileEntry struct {
+	Name string `json:"name"`
+
+	// Type is the content type of the part, e.g. application/x-directory
+	// for directories.
+	Type string `json:"type,omitempty"`
+	Size int64  `json:"size"`
+}
+
+// RecordedResponse is the response of an Interaction.
+type RecordedResponse struct {
+	Status  int         `json:"status"`
+	Header  http.Header `json:"header,omitempty"`
+	Body    []byte      `json:"body,omitempty"`
+	Trailer http.Header `json:"trailer,omitempty"`
+}
+
+// NewRecordingHandler returns a handler that passes requests on to h, and
+// writes each request and its full response to the cassette w once the
+// response is complete. Responses are kept in memory until then.
+func NewRecordingHandler(h http.Handler, w io.Writer) http.Handler {
+	return &recordingHandler{
+		next: h,
+		enc:  json.NewEncoder(w),
+	}
+}
+
+type recordingHandler struct {
+	next http.Handler
+
+	l   sync.Mutex
+	enc *json.Encoder
+}
+
+func (h *recordingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	in := Interaction{
+		Path:      r.URL.Path,
+		Arguments: query["arg"],
+	}
+	query.Del("arg")
+	if len(query) > 0 {
+		in.Options = query
+	}
+
+	var files <-chan []FileEntry
+	if mediatype, params, _ := mime.ParseMediaType(r.Header.Get(contentTypeHeader)); mediatype == "multipart/form-data" {
+		// read the manifest from a copy of the body as the handler reads
+		// it, so that file contents are not kept around.
+		pr, pw := io.Pipe()
+		r.Body = &teeBody{Reader: io.TeeReader(r.Body, pw), body: r.Body, pw: pw}
+		files = readManifest(pr, params["boundary"])
+	}
+
+	rw := &recordingWriter{ResponseWriter: w}
+	h.next.ServeHTTP(rw, r)
+
+	if files != nil {
+		r.Body.Close()
+		in.Files = <-files
+	}
+
+	in.Response = RecordedResponse{
+		Status: rw.status,
+		Header: rw.header,
+		Body:   rw.body,
+	}
+	if in.Response.Status == 0 {
+		in.Response.Status = http.StatusOK
+		in.Response.Header = w.Header().Clone()
+	}
+	in.Response.Trailer = trailers(w.Header())
+
+	h.l.Lock()
+	defer h.l.Unlock()
+	if err := h.enc.Encode(in); err != nil {
+		log.Errorf("error recording request to %s: %s", in.Path, err)
+	}
+}
+
+// trailers returns the trailers set in h after the header was written.
+func trailers(h http.Header) http.Header {
+	t := make(http.Header)
+	for _, declared := range h.Values("Trailer") {
+		for key := range strings.SplitSeq(declared, ",") {
+			key = http.CanonicalHeaderKey(strings.TrimSpace(key))
+			if v, ok := h[key]; ok {
+				t[key] = v
+			}
+		}
+	}
+	for key, v := range h {
+		if name, ok := strings.CutPrefix(key, http.TrailerPrefix); ok {
+			t[http.CanonicalHeaderKey(name)] = v
+		}
+	}
+
+	if len(t) == 0 {
+		return nil
+	}
+	return t
+}
+
+// readManifest lists the files of the multipart body read from r. It drains
+// r, so that the writing side never blocks.
+func readManifest(r *io.PipeReader, boundary string) <-chan []FileEntry {
+	ch := make(chan []FileEntry, 1)
+
+	go func() {
+		defer io.Copy(io.Discard, r)
+
+		var files []FileEntry
+		defer func() { ch <- files }()
+
+		mr := multipart.NewReader(r, boundary)
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				return
+			}
+
+			name := part.FileName()
+			if unescaped, err := url.QueryUnescape(name); err == nil {
+				name = unescaped
+			}
+			contentType, _, _ := mime.ParseMediaType(part.Header.Get(contentTypeHeader))
+
+			size, err := io.Copy(io.Discard, part)
+			files = append(files, FileEntry{
+				Name: path.Clean("/" + name)[1:],
+				Type: contentType,
+				Size: size,
+			})
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+// teeBody is a request body that copies what is read from it to a pipe,
+// which is closed along with the body.
+type teeBody struct {
+	io.Reader
+	body io.Closer
+	pw   *io.PipeWriter
+
+	once sync.Once
+}
+
+func (b *teeBody) Close() error {
+	b.once.Do(func() { b.pw.Close() })
+	return b.body.Close()
+}
+
+// recordingWriter is a http.ResponseWriter that keeps a copy of the
+// response.
+type recordingWriter struct {
+	http.ResponseWriter
+
+	status int
+	header http.Header
+	body   []byte
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+		w.header = w.ResponseWriter.Header().Clone()
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.body = append(w.body, b[:n]...)
+	return n, err
+}
+
+func (w *recordingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
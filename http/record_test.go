@@ -0,0 +1,117 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ipfs/boxo/files"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+// execute runs the command at path with exe and returns the values it
+// emitted.
+func execute(t *testing.T, exe cmds.Executor, path []string, f files.Directory) ([]any, error) {
+	t.Helper()
+
+	req, err := cmds.NewRequest(context.Background(), path, nil, nil, nil, cmdRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Files = f
+
+	re, res := cmds.NewChanResponsePair(req)
+	errCh := make(chan error, 1)
+	go func() {
+		err := exe.Execute(req, re, nil)
+		if err != nil {
+			re.CloseWithError(err)
+		}
+		errCh <- err
+	}()
+
+	var vs []any
+	for {
+		v, err := res.Next()
+		if err == io.EOF {
+			return vs, <-errCh
+		}
+		if err != nil {
+			<-errCh
+			return vs, err
+		}
+		if s, ok := v.(*string); ok {
+			v = *s
+		}
+		vs = append(vs, v)
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	env := newTestEnv(t)
+
+	var cassette bytes.Buffer
+	srv := httptest.NewServer(NewRecordingHandler(NewHandler(env, cmdRoot, originCfg(defaultOrigins)), &cassette))
+	c := NewClient(srv.URL)
+
+	body := func() files.Directory {
+		return files.NewMapDirectory(map[string]files.Node{
+			"stdin": files.NewBytesFile([]byte("replayed body")),
+		})
+	}
+
+	echo, err := execute(t, c, []string{"echo"}, body())
+	if err != nil {
+		t.Fatal(err)
+	}
+	lateErr, err := execute(t, c, []string{"lateerror"}, nil)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	srv.Close()
+
+	ins, err := ReadCassette(bytes.NewReader(cassette.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ins) != 2 {
+		t.Fatalf("expected 2 interactions, got %d", len(ins))
+	}
+	want := []FileEntry{{Name: "stdin", Type: "application/octet-stream", Size: int64(len("replayed body"))}}
+	if !reflect.DeepEqual(ins[0].Files, want) {
+		t.Fatalf("unexpected file manifest %v", ins[0].Files)
+	}
+	if ins[1].Response.Trailer.Get(StreamErrHeader) == "" {
+		t.Fatal("expected the stream error trailer to be recorded")
+	}
+
+	exe, err := NewReplayExecutor(&cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vs, err := execute(t, exe, []string{"echo"}, body())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(vs, echo) {
+		t.Fatalf("replayed %v, recorded %v", vs, echo)
+	}
+
+	vs, err = execute(t, exe, []string{"lateerror"}, nil)
+	if err == nil || err.Error() != "an error occurred" {
+		t.Fatalf("expected the recorded error, got %v", err)
+	}
+	if !reflect.DeepEqual(vs, lateErr) {
+		t.Fatalf("replayed %v, recorded %v", vs, lateErr)
+	}
+
+	// every interaction is only replayed once
+	if _, err := execute(t, exe, []string{"lateerror"}, nil); !errors.Is(err, ErrNotRecorded) {
+		t.Fatalf("expected ErrNotRecorded, got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+// ErrNotRecorded is returned by the replay executor for requests that are
+// not in its cassette.
+var ErrNotRecorded = errors.New("request not recorded")
+
+// ReadCassette reads the interactions of a cassette written by a recording
+// handler.
+func ReadCassette(r io.Reader) ([]Interaction, error) {
+	var ins []Interaction
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var in Interaction
+		if err := dec.Decode(&in); err != nil {
+			if err == io.EOF {
+				return ins, nil
+			}
+			return nil, fmt.Errorf("invalid cassette: %w", err)
+		}
+		ins = append(ins, in)
+	}
+}
+
+// NewReplayExecutor returns an executor that serves the responses recorded
+// in the cassette r instead of sending requests to a server. Requests are
+// executed like by a client constructed with opts; a request gets the
+// response of the first interaction with the same path, options and
+// arguments that has not been replayed yet.
+func NewReplayExecutor(r io.Reader, opts ...ClientOpt) (cmds.Executor, error) {
+	ins, err := ReadCassette(r)
+	if err != nil {
+		return nil, err
+	}
+
+	rt := &replayTransport{interactions: ins, replayed: make([]bool, len(ins))}
+	opts = append(opts, ClientWithHTTPClient(&http.Client{Transport: rt}))
+	return NewClient("replay", opts...), nil
+}
+
+type replayTransport struct {
+	l            sync.Mutex
+	interactions []Interaction
+	replayed     []bool
+}
+
+func (t *replayTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Body != nil {
+		r.Body.Close()
+	}
+
+	query := r.URL.Query()
+	args := query["arg"]
+	query.Del("arg")
+
+	t.l.Lock()
+	defer t.l.Unlock()
+
+	for i, in := range t.interactions {
+		if t.replayed[i] || in.Path != r.URL.Path ||
+			!slices.Equal(in.Arguments, args) ||
+			!maps.EqualFunc(in.Options, query, slices.Equal) {
+			continue
+		}
+		t.replayed[i] = true
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.Status, http.StatusText(in.Response.Status)),
+			StatusCode:    in.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        in.Response.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Trailer:       in.Response.Trailer.Clone(),
+			Request:       r,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s?%s", ErrNotRecorded, r.URL.Path, r.URL.RawQuery)
+}
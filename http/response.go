@@ -3,6 +3,7 @@ package http
 import (
 	"errors"
 	"io"
+	"iter"
 	"net/http"
 	"reflect"
 	"strings"
@@ -13,15 +14,24 @@ import (
 var (
 	// MIMEEncodings maps Content-Type values to encoding types for response parsing.
 	MIMEEncodings = map[string]cmds.EncodingType{
+		"application/cbor":                 cmds.CBOR,
 		"application/gzip":                 cmds.OctetStream,
 		"application/json":                 cmds.JSON,
+		"application/json-seq":             cmds.JSONSeq,
 		"application/octet-stream":         cmds.OctetStream,
+		"application/protobuf":             cmds.Protobuf,
+		"application/toml":                 cmds.TOML,
 		"application/vnd.ipfs.ipns-record": cmds.OctetStream,
 		"application/vnd.ipld.car":         cmds.OctetStream,
+		"application/vnd.ipld.dag-json":    cmds.DAGJSON,
 		"application/vnd.ipld.raw":         cmds.OctetStream,
+		"application/x-ndjson":             cmds.NDJSON,
 		"application/x-tar":                cmds.OctetStream,
 		"application/xml":                  cmds.XML,
+		"application/yaml":                 cmds.YAML,
 		"application/zip":                  cmds.OctetStream,
+		"text/csv":                         cmds.CSV,
+		"text/event-stream":                cmds.EventStream,
 		"text/plain":                       cmds.Text,
 	}
 )
@@ -37,6 +47,15 @@ type Response struct {
 	dec cmds.Decoder
 
 	initErr *cmds.Error
+
+	// client is used to fetch the following pages
+	client *client
+
+	progress func(cmds.Progress)
+}
+
+func (res *Response) SetProgressHandler(f func(cmds.Progress)) {
+	res.progress = f
 }
 
 func (res *Response) Request() *cmds.Request {
@@ -59,6 +78,60 @@ func (res *Response) Error() *cmds.Error {
 	}
 }
 
+// NextCursor returns the cursor of the next page, once all values of the
+// response have been read.
+func (res *Response) NextCursor() string {
+	if res.err != io.EOF {
+		return ""
+	}
+
+	if cursor := res.res.Trailer.Get(NextCursorHeader); cursor != "" {
+		return cursor
+	}
+	return res.res.Header.Get(NextCursorHeader)
+}
+
+// Header returns the HTTP headers of the response.
+func (res *Response) Header() http.Header {
+	return res.res.Header
+}
+
+// Trailer returns the HTTP trailers of the response. They are only set once
+// all values of the response have been read.
+func (res *Response) Trailer() http.Header {
+	return res.res.Trailer
+}
+
+// All returns an iterator over the values of the response, followed by the
+// values of all following pages, which are requested as needed. Iteration
+// stops after the first error.
+func (res *Response) All() iter.Seq2[any, error] {
+	return func(yield func(any, error) bool) {
+		cur := res
+		for {
+			v, err := cur.Next()
+			if err == io.EOF {
+				cursor := cur.NextCursor()
+				if cursor == "" || cur.client == nil {
+					return
+				}
+
+				progress := cur.progress
+				if cur, err = cur.client.nextPage(cur.req, cursor); err != nil {
+					yield(nil, err)
+					return
+				}
+				cur.progress = progress
+				continue
+			}
+
+			if !yield(v, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
 func (res *Response) Length() uint64 {
 	return res.length
 }
@@ -93,6 +166,14 @@ func (res *Response) Next() (any, error) {
 
 	m := &cmds.MaybeError{Value: value}
 	err := res.dec.Decode(m)
+	for err == nil && m.Progress != nil {
+		if res.progress != nil {
+			res.progress(*m.Progress)
+		}
+
+		m = &cmds.MaybeError{Value: value}
+		err = res.dec.Decode(m)
+	}
 	if err != nil {
 		if err == io.EOF {
 			// handle errors from headers
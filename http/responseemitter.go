@@ -1,25 +1,36 @@
 package http
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	cmds "github.com/ipfs/go-ipfs-cmds"
 )
 
 var (
 	// AllowedExposedHeadersArr defines the default Access-Control-Expose-Headers.
-	AllowedExposedHeadersArr = []string{streamHeader, channelHeader, extraContentLengthHeader}
+	AllowedExposedHeadersArr = []string{streamHeader, channelHeader, extraContentLengthHeader, NextCursorHeader, warningHeader, deprecationHeader, APIVersionHeader}
 	// AllowedExposedHeaders is the list of defaults Access-Control-Expose-Headers separated by comma.
 	AllowedExposedHeaders = strings.Join(AllowedExposedHeadersArr, ", ")
 
 	mimeTypes = map[cmds.EncodingType]string{
 		cmds.Protobuf:    "application/protobuf",
 		cmds.JSON:        "application/json",
+		cmds.NDJSON:      "application/x-ndjson",
+		cmds.JSONSeq:     "application/json-seq",
+		cmds.CBOR:        "application/cbor",
+		cmds.DAGJSON:     "application/vnd.ipld.dag-json",
+		cmds.YAML:        "application/yaml",
+		cmds.TOML:        "application/toml",
+		cmds.Table:       "text/plain",
+		cmds.CSV:         "text/csv",
+		cmds.EventStream: "text/event-stream",
 		cmds.XML:         "application/xml",
 		cmds.Text:        "text/plain",
 		cmds.OctetStream: "application/octet-stream",
@@ -51,6 +62,24 @@ func NewResponseEmitter(w http.ResponseWriter, method string, req *cmds.Request,
 // ResponseEmitterOption is the type describing options to the NewResponseEmitter function.
 type ResponseEmitterOption func(*responseEmitter)
 
+// withProgressFrames returns a ResponseEmitterOption that makes the emitter
+// send progress reports as frames in JSON responses. Only clients that can
+// tell them apart from values should request them.
+func withProgressFrames(enabled bool) ResponseEmitterOption {
+	return func(re *responseEmitter) {
+		re.progressFrames = enabled
+	}
+}
+
+// withKeepalive returns a ResponseEmitterOption setting the interval of the
+// keepalive comments sent in event streams. Keepalives are disabled if d is
+// not positive.
+func withKeepalive(d time.Duration) ResponseEmitterOption {
+	return func(re *responseEmitter) {
+		re.keepalive = d
+	}
+}
+
 // withRequestBodyEOFChan return a ResponseEmitterOption needed to gracefully handle
 // the case where the handler wants to send data and the request data has not been read
 // completely yet.
@@ -81,6 +110,15 @@ type responseEmitter struct {
 
 	bodyEOFChan <-chan struct{}
 
+	keepalive     time.Duration
+	stopKeepalive chan struct{}
+
+	progressFrames bool
+	// pendingProgress is the latest progress report emitted before the
+	// preamble was sent.
+	pendingProgress *cmds.Progress
+	preambleSent    bool
+
 	streaming   bool
 	closed      bool
 	once        sync.Once
@@ -113,6 +151,17 @@ func (re *responseEmitter) Emit(value any) error {
 		return nil
 	}
 
+	if f, ok := re.w.(http.Flusher); ok {
+		defer f.Flush()
+	}
+
+	if p := re.pendingProgress; p != nil {
+		re.pendingProgress = nil
+		if err := re.encodeProgress(*p); err != nil {
+			return err
+		}
+	}
+
 	// ignore those
 	if value == nil {
 		return nil
@@ -124,10 +173,6 @@ func (re *responseEmitter) Emit(value any) error {
 		isSingle = true
 	}
 
-	if f, ok := re.w.(http.Flusher); ok {
-		defer f.Flush()
-	}
-
 	switch v := value.(type) {
 	case error:
 		return re.closeWithError(v)
@@ -145,6 +190,48 @@ func (re *responseEmitter) Emit(value any) error {
 	return err
 }
 
+// EmitProgress sends a progress report as a ProgressEvent in event streams,
+// and as a cmds.ProgressFrame in JSON responses to clients that requested
+// them. It is dropped otherwise, e.g. in streams of bytes.
+//
+// Progress doesn't send the preamble, as the first value or error decides
+// how the response is framed. Until then, only the latest report is kept and
+// sent ahead of the first value.
+func (re *responseEmitter) EmitProgress(p cmds.Progress) error {
+	re.l.Lock()
+	defer re.l.Unlock()
+
+	if re.closed {
+		return cmds.ErrClosedEmitter
+	}
+	if !re.preambleSent {
+		re.pendingProgress = &p
+		return nil
+	}
+
+	if f, ok := re.w.(http.Flusher); ok {
+		defer f.Flush()
+	}
+	return re.encodeProgress(p)
+}
+
+// encodeProgress writes a progress report, if the response can carry them.
+// It must be called with re.l held, after the preamble was sent.
+func (re *responseEmitter) encodeProgress(p cmds.Progress) error {
+	if re.method == http.MethodHead || re.streaming {
+		return nil
+	}
+
+	switch {
+	case re.encType == cmds.EventStream:
+		return cmds.NewEventStreamEncoder(re.w).Encode(p)
+	case re.encType == cmds.JSON && re.progressFrames:
+		return json.NewEncoder(re.w).Encode(cmds.ProgressFrame{Progress: p})
+	default:
+		return nil
+	}
+}
+
 func (re *responseEmitter) SetEncodingType(encType cmds.EncodingType) {
 	re.l.Lock()
 	defer re.l.Unlock()
@@ -159,6 +246,14 @@ func (re *responseEmitter) SetContentType(contentType string) {
 	re.contentType = contentType
 }
 
+func (re *responseEmitter) SetNextCursor(cursor string) {
+	re.l.Lock()
+	defer re.l.Unlock()
+
+	// a header before the preamble, a trailer after
+	re.w.Header().Set(NextCursorHeader, cursor)
+}
+
 func (re *responseEmitter) SetLength(l uint64) {
 	re.l.Lock()
 	defer re.l.Unlock()
@@ -216,8 +311,21 @@ func (re *responseEmitter) closeWithError(err error) error {
 
 	if setErrTrailer && err != nil {
 		re.w.Header().Set(StreamErrHeader, err.Error())
+
+		// EventSources can't read trailers
+		if re.encType == cmds.EventStream {
+			if err := cmds.NewEventStreamEncoder(re.w).Encode(err); err != nil {
+				log.Error("error sending error event", err)
+			}
+			if f, ok := re.w.(http.Flusher); ok {
+				f.Flush()
+			}
+		}
 	}
 
+	if re.stopKeepalive != nil {
+		close(re.stopKeepalive)
+	}
 	re.closed = true
 
 	return nil
@@ -244,6 +352,11 @@ func (re *responseEmitter) sendErr(err *cmds.Error) {
 	// on json.
 	encType := re.encType
 	enc, ok := cmds.Encoders[encType]
+	switch encType {
+	case cmds.Protobuf, cmds.Table, cmds.CSV:
+		// these encodings can't represent errors
+		ok = false
+	}
 	if !ok {
 		encType = cmds.JSON
 		enc = cmds.Encoders[encType]
@@ -254,7 +367,12 @@ func (re *responseEmitter) sendErr(err *cmds.Error) {
 
 	// Set the status from the error code.
 	status := http.StatusInternalServerError
-	if err.Code == cmds.ErrClient {
+	switch {
+	case encType == cmds.EventStream:
+		// EventSources can't read the body of failed responses, so the
+		// error is sent as an event instead.
+		status = http.StatusOK
+	case err.Code == cmds.ErrClient:
 		status = http.StatusBadRequest
 	}
 	re.w.WriteHeader(status)
@@ -268,6 +386,7 @@ func (re *responseEmitter) sendErr(err *cmds.Error) {
 }
 
 func (re *responseEmitter) doPreamble(value any) {
+	re.preambleSent = true
 	h := re.w.Header()
 
 	// Common Headers
@@ -278,7 +397,7 @@ func (re *responseEmitter) doPreamble(value any) {
 	h.Set("Access-Control-Expose-Headers", AllowedExposedHeaders)
 
 	// Set up our potential trailer
-	h.Set("Trailer", StreamErrHeader)
+	h.Set("Trailer", StreamErrHeader+", "+NextCursorHeader)
 
 	// If we have a request body, make sure we close the body
 	// if we want to write before completing reading.
@@ -316,6 +435,11 @@ func (re *responseEmitter) doPreamble(value any) {
 		// don't set stream/channel header
 	default:
 		h.Set(channelHeader, "1")
+
+		if re.encType == cmds.EventStream && re.keepalive > 0 && re.method != http.MethodHead {
+			re.stopKeepalive = make(chan struct{})
+			go re.sendKeepalives(re.stopKeepalive)
+		}
 	}
 
 	if mime == "" {
@@ -338,6 +462,32 @@ func (re *responseEmitter) doPreamble(value any) {
 	re.w.WriteHeader(http.StatusOK)
 }
 
+// sendKeepalives periodically writes a comment to the event stream, so that
+// proxies don't time out the connection while the command is idle.
+func (re *responseEmitter) sendKeepalives(stop <-chan struct{}) {
+	t := time.NewTicker(re.keepalive)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+		case <-stop:
+			return
+		case <-re.req.Context.Done():
+			return
+		}
+
+		re.l.Lock()
+		if !re.closed {
+			io.WriteString(re.w, ": keepalive\n\n")
+			if f, ok := re.w.(http.Flusher); ok {
+				f.Flush()
+			}
+		}
+		re.l.Unlock()
+	}
+}
+
 func flushCopy(w io.Writer, r io.Reader) error {
 	buf := make([]byte, 4096)
 	f, ok := w.(http.Flusher)
@@ -3,10 +3,12 @@ package http
 import (
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"testing"
 
 	cmds "github.com/ipfs/go-ipfs-cmds"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 func TestSetEncodingType(t *testing.T) {
@@ -136,8 +138,17 @@ func TestMIMEEncodingsMapping(t *testing.T) {
 		encType  cmds.EncodingType
 	}{
 		{"application/json", cmds.JSON},
+		{"application/x-ndjson", cmds.NDJSON},
+		{"application/json-seq", cmds.JSONSeq},
+		{"application/cbor", cmds.CBOR},
+		{"application/protobuf", cmds.Protobuf},
+		{"application/yaml", cmds.YAML},
+		{"application/toml", cmds.TOML},
+		{"application/vnd.ipld.dag-json", cmds.DAGJSON},
 		{"application/xml", cmds.XML},
 		{"text/plain", cmds.Text},
+		{"text/csv", cmds.CSV},
+		{"text/event-stream", cmds.EventStream},
 		{"application/octet-stream", cmds.OctetStream},
 		{"application/gzip", cmds.OctetStream},
 		{"application/x-tar", cmds.OctetStream},
@@ -160,3 +171,144 @@ func TestMIMEEncodingsMapping(t *testing.T) {
 		})
 	}
 }
+
+// TestStructuredEncodings verifies that values can be requested in every
+// structured encoding and decoded again from the response.
+func TestStructuredEncodings(t *testing.T) {
+	testCases := []struct {
+		encoding            cmds.EncodingType
+		expectedContentType string
+	}{
+		{cmds.NDJSON, "application/x-ndjson"},
+		{cmds.JSONSeq, "application/json-seq"},
+		{cmds.CBOR, "application/cbor"},
+		{cmds.DAGJSON, "application/vnd.ipld.dag-json"},
+		{cmds.YAML, "application/yaml"},
+		{cmds.TOML, "application/toml"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.encoding), func(t *testing.T) {
+			_, srv := getTestServer(t, nil, true)
+			defer srv.Close()
+
+			req, err := http.NewRequest("POST", srv.URL+"/version?encoding="+string(tc.encoding), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Origin", "http://localhost")
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			contentType := strings.Split(resp.Header.Get("Content-Type"), ";")[0]
+			if contentType != tc.expectedContentType {
+				t.Errorf("expected Content-Type %q, got %q", tc.expectedContentType, contentType)
+			}
+
+			res, err := parseResponse(resp, &cmds.Request{Command: cmdRoot.Subcommands["version"]})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			v, err := res.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if vo, ok := v.(*VersionOutput); !ok || vo.Version != "0.1.2" {
+				t.Fatalf("unexpected value %#v", v)
+			}
+
+			if _, err = res.Next(); err != io.EOF {
+				t.Fatalf("expected EOF, got %v", err)
+			}
+		})
+	}
+}
+
+func TestProtobufEncoding(t *testing.T) {
+	_, srv := getTestServer(t, nil, true)
+	defer srv.Close()
+
+	req, err := http.NewRequest("POST", srv.URL+"/protobuf?encoding=protobuf", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Origin", "http://localhost")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get(channelHeader) == "" {
+		t.Errorf("expected %s header to be set", channelHeader)
+	}
+
+	res, err := parseResponse(resp, &cmds.Request{Command: cmdRoot.Subcommands["protobuf"]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exp := range []string{"hello", "world"} {
+		v, err := res.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sv, ok := v.(*wrapperspb.StringValue); !ok || sv.GetValue() != exp {
+			t.Fatalf("expected %q, got %#v", exp, v)
+		}
+	}
+
+	if _, err = res.Next(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	testCases := []struct {
+		query      string
+		statusCode int
+		body       string
+	}{
+		{"filter=.Version", http.StatusOK, "\"0.1.2\"\n"},
+		{"filter=" + url.QueryEscape("{Version, Repo}"), http.StatusOK, "{\"Repo\":\"4\",\"Version\":\"0.1.2\"}\n"},
+		{"filter=.Version&encoding=text", http.StatusOK, "0.1.2\n"},
+		{"filter=" + url.QueryEscape(".Version |"), http.StatusBadRequest, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			_, srv := getTestServer(t, nil, true)
+			defer srv.Close()
+
+			req, err := http.NewRequest("POST", srv.URL+"/version?"+tc.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Origin", "http://localhost")
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.statusCode {
+				t.Fatalf("expected status %d, got %d", tc.statusCode, resp.StatusCode)
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.statusCode == http.StatusOK && string(body) != tc.body {
+				t.Errorf("expected body %q, got %q", tc.body, body)
+			}
+		})
+	}
+}
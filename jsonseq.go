@@ -0,0 +1,100 @@
+package cmds
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// recordSeparator is the ASCII RS character that starts every record of an
+// RFC 7464 JSON text sequence.
+const recordSeparator = 0x1E
+
+// JSONSeqEncoder encodes values as an RFC 7464 JSON text sequence: every
+// value is prefixed with an RS character and terminated by a line feed.
+type JSONSeqEncoder struct {
+	w io.Writer
+}
+
+// NewJSONSeqEncoder returns an encoder writing a JSON text sequence to w.
+func NewJSONSeqEncoder(w io.Writer) *JSONSeqEncoder {
+	return &JSONSeqEncoder{w: w}
+}
+
+func (e *JSONSeqEncoder) Encode(v any) error {
+	buf := new(bytes.Buffer)
+	buf.WriteByte(recordSeparator)
+
+	// json.Encoder already terminates the value with a line feed.
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		return err
+	}
+
+	_, err := e.w.Write(buf.Bytes())
+	return err
+}
+
+// JSONSeqDecoder decodes values from an RFC 7464 JSON text sequence.
+type JSONSeqDecoder struct {
+	r *bufio.Reader
+}
+
+// NewJSONSeqDecoder returns a decoder reading a JSON text sequence from r.
+func NewJSONSeqDecoder(r io.Reader) *JSONSeqDecoder {
+	return &JSONSeqDecoder{r: bufio.NewReader(r)}
+}
+
+func (d *JSONSeqDecoder) Decode(v any) error {
+	for {
+		rec, err := d.next()
+		if err != nil {
+			return err
+		}
+
+		// empty records are allowed by the RFC and carry no value
+		rec = bytes.TrimSpace(rec)
+		if len(rec) == 0 {
+			continue
+		}
+
+		return json.Unmarshal(rec, v)
+	}
+}
+
+// next returns the next record, without the leading RS. Records end with
+// the line feed that terminates their JSON text, so they are returned as
+// soon as they are complete. Texts spanning several lines are read until
+// they are valid JSON, or until the next RS.
+func (d *JSONSeqDecoder) next() ([]byte, error) {
+	// skip everything up to and including the first RS
+	if _, err := d.r.ReadBytes(recordSeparator); err != nil {
+		return nil, err
+	}
+
+	var rec []byte
+	for {
+		line, err := d.r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		// an RS within the line starts the next record, so this one was
+		// truncated
+		if i := bytes.IndexByte(line, recordSeparator); i >= 0 {
+			d.r = bufio.NewReader(io.MultiReader(bytes.NewReader(line[i:]), d.r))
+			return append(rec, line[:i]...), nil
+		}
+
+		rec = append(rec, line...)
+		if err == io.EOF {
+			if len(bytes.TrimSpace(rec)) == 0 {
+				return nil, io.EOF
+			}
+			return rec, nil
+		}
+		if trimmed := bytes.TrimSpace(rec); len(trimmed) == 0 || json.Valid(trimmed) {
+			return rec, nil
+		}
+	}
+}
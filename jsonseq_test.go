@@ -0,0 +1,132 @@
+package cmds
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONSeqRoundTrip(t *testing.T) {
+	buf := new(bytes.Buffer)
+	enc := NewJSONSeqEncoder(buf)
+
+	for _, v := range []Foo{{1}, {2}} {
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Encode(Error{Message: "some error"}); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := "\x1e{\"Bar\":1}\n\x1e{\"Bar\":2}\n\x1e{\"Message\":\"some error\",\"Code\":0,\"Type\":\"error\"}\n"
+	if buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+
+	dec := NewJSONSeqDecoder(buf)
+	for _, exp := range []*Foo{{1}, {2}} {
+		m := &MaybeError{Value: &Foo{}}
+		if err := dec.Decode(m); err != nil {
+			t.Fatal(err)
+		}
+		v, err := m.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(v, exp) {
+			t.Fatalf("expected %v, got %v", exp, v)
+		}
+	}
+
+	m := &MaybeError{Value: &Foo{}}
+	if err := dec.Decode(m); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Get(); err == nil || err.Error() != "some error" {
+		t.Fatalf("expected error %q, got %v", "some error", err)
+	}
+
+	if err := dec.Decode(&MaybeError{}); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func TestJSONSeqDecoderSkipsEmptyRecords(t *testing.T) {
+	dec := NewJSONSeqDecoder(strings.NewReader("\x1e\n\x1e\x1e{\"Bar\":3}"))
+
+	var v Foo
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Bar != 3 {
+		t.Fatalf("expected 3, got %d", v.Bar)
+	}
+
+	if err := dec.Decode(&v); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func TestJSONSeqDecoderStreams(t *testing.T) {
+	r, w := io.Pipe()
+	defer r.Close()
+
+	next := make(chan struct{})
+	go func() {
+		enc := NewJSONSeqEncoder(w)
+		enc.Encode(Foo{1})
+		// the next record is only sent after the first was decoded
+		<-next
+		enc.Encode(Foo{2})
+		w.Close()
+	}()
+
+	dec := NewJSONSeqDecoder(r)
+	decoded := make(chan Foo, 1)
+	go func() {
+		var v Foo
+		if err := dec.Decode(&v); err != nil {
+			t.Error(err)
+		}
+		decoded <- v
+	}()
+
+	select {
+	case v := <-decoded:
+		if v.Bar != 1 {
+			t.Fatalf("expected 1, got %d", v.Bar)
+		}
+	case <-time.After(5 * time.Second):
+		close(next)
+		t.Fatal("the record wasn't decoded before the next one was sent")
+	}
+	close(next)
+
+	var v Foo
+	if err := dec.Decode(&v); err != nil || v.Bar != 2 {
+		t.Fatalf("expected 2, got %d, %v", v.Bar, err)
+	}
+	if err := dec.Decode(&v); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func TestJSONSeqDecoderMultiline(t *testing.T) {
+	dec := NewJSONSeqDecoder(strings.NewReader("\x1e{\n  \"Bar\": 4\n}\n\x1e{\"Bar\"\x1e{\"Bar\":5}\n"))
+
+	var v Foo
+	if err := dec.Decode(&v); err != nil || v.Bar != 4 {
+		t.Fatalf("expected 4, got %d, %v", v.Bar, err)
+	}
+	// the truncated record fails, and the following one is still read
+	if err := dec.Decode(&v); err == nil {
+		t.Fatal("expected an error for the truncated record")
+	}
+	if err := dec.Decode(&v); err != nil || v.Bar != 5 {
+		t.Fatalf("expected 5, got %d, %v", v.Bar, err)
+	}
+}
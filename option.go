@@ -2,9 +2,15 @@ package cmds
 
 import (
 	"fmt"
+	"net/url"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multiaddr"
 )
 
 // Types of Command options
@@ -20,6 +26,86 @@ const (
 	Strings = reflect.Array
 )
 
+// Rich types of Command options. They are not reflect kinds, but share
+// their space so that Option.Type can return them. Values of these options
+// have the Go type listed in OptionGoType.
+const (
+	Duration  = reflect.Kind(iota + 64) // time.Duration, e.g. 1m30s
+	ByteSize                            // uint64 number of bytes, e.g. 256KiB or 1GB
+	URL                                 // *url.URL
+	Multiaddr                           // multiaddr.Multiaddr
+	CID                                 // cid.Cid
+	KeyValue                            // map[string]string, from key=value pairs
+	Tristate                            // TriBool, a flag that can be unset, true or false
+)
+
+var richTypes = map[reflect.Kind]struct {
+	name string
+	typ  reflect.Type
+}{
+	Duration:  {"duration", reflect.TypeFor[time.Duration]()},
+	ByteSize:  {"size", reflect.TypeFor[uint64]()},
+	URL:       {"url", reflect.TypeFor[*url.URL]()},
+	Multiaddr: {"multiaddr", reflect.TypeFor[multiaddr.Multiaddr]()},
+	CID:       {"cid", reflect.TypeFor[cid.Cid]()},
+	KeyValue:  {"key=value", reflect.TypeFor[map[string]string]()},
+	Tristate:  {"bool", reflect.TypeFor[TriBool]()},
+}
+
+// OptionTypeName returns the name of an option type for help texts.
+func OptionTypeName(kind reflect.Kind) string {
+	if rt, ok := richTypes[kind]; ok {
+		return rt.name
+	}
+	return kind.String()
+}
+
+// schemaTypes are the names of option types in schemas describing commands,
+// e.g. those of plugins and server capabilities.
+var schemaTypes = map[reflect.Kind]string{
+	Bool:      "bool",
+	Int:       "int",
+	Uint:      "uint",
+	Int64:     "int64",
+	Uint64:    "uint64",
+	Float:     "float",
+	String:    "string",
+	Strings:   "strings",
+	Duration:  "duration",
+	ByteSize:  "size",
+	URL:       "url",
+	Multiaddr: "multiaddr",
+	CID:       "cid",
+	KeyValue:  "key=value",
+	Tristate:  "tristate",
+}
+
+// OptionSchemaType returns the name of an option type in schemas describing
+// commands.
+func OptionSchemaType(kind reflect.Kind) string {
+	if name, ok := schemaTypes[kind]; ok {
+		return name
+	}
+	return kind.String()
+}
+
+// ParseOptionSchemaType returns the option type with the given name in
+// schemas describing commands.
+func ParseOptionSchemaType(name string) (reflect.Kind, bool) {
+	for kind, n := range schemaTypes {
+		if n == name {
+			return kind, true
+		}
+	}
+	return reflect.Invalid, false
+}
+
+// OptionGoType returns the Go type of the values of options of the rich
+// type kind, or nil if kind is a reflect kind.
+func OptionGoType(kind reflect.Kind) reflect.Type {
+	return richTypes[kind].typ
+}
+
 type OptMap map[string]any
 
 // Option is used to specify a field that will be provided by a consumer
@@ -41,6 +127,12 @@ type option struct {
 	kind        reflect.Kind
 	description string
 	defaultVal  any
+	constraints Constraints
+	env         []string
+	deprecation *Deprecation
+
+	// versionDefaults maps API versions to the defaults they introduced
+	versionDefaults map[int]any
 }
 
 func (o *option) Name() string {
@@ -62,15 +154,26 @@ func (o *option) Description() string {
 	if !strings.HasSuffix(o.description, ".") {
 		o.description += "."
 	}
+
+	desc := o.description
+	if o.deprecation != nil {
+		desc = fmt.Sprintf("%s %s", o.deprecation, desc)
+	}
 	if o.defaultVal != nil {
-		if strings.Contains(o.description, "<<default>>") {
-			return strings.Replace(o.description, "<<default>>",
+		if strings.Contains(desc, "<<default>>") {
+			desc = strings.Replace(desc, "<<default>>",
 				fmt.Sprintf("Default: %v.", o.defaultVal), -1)
 		} else {
-			return fmt.Sprintf("%s Default: %v.", o.description, o.defaultVal)
+			desc = fmt.Sprintf("%s Default: %v.", desc, o.defaultVal)
 		}
 	}
-	return o.description
+	if len(o.env) > 0 {
+		desc += fmt.Sprintf(" Env: %s.", strings.Join(o.env, ", "))
+	}
+	if c := o.constraints.String(); c != "" {
+		desc += " " + c
+	}
+	return desc
 }
 
 type converter func(string) (any, error)
@@ -84,6 +187,19 @@ var converters = map[reflect.Kind]converter{
 
 		return strconv.ParseBool(v)
 	},
+	Tristate: func(v string) (any, error) {
+		if v == "" {
+			return True, nil
+		}
+		b, err := strconv.ParseBool(strings.ToLower(v))
+		if err != nil {
+			return nil, err
+		}
+		if b {
+			return True, nil
+		}
+		return False, nil
+	},
 	Int: func(v string) (any, error) {
 		val, err := strconv.ParseInt(v, 0, 32)
 		if err != nil {
@@ -121,12 +237,41 @@ var converters = map[reflect.Kind]converter{
 	Strings: func(v string) (any, error) {
 		return v, nil
 	},
+	Duration: func(v string) (any, error) {
+		return time.ParseDuration(v)
+	},
+	ByteSize: func(v string) (any, error) {
+		return ParseByteSize(v)
+	},
+	URL: func(v string) (any, error) {
+		u, err := url.Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme == "" {
+			return nil, fmt.Errorf("URL %q has no scheme", v)
+		}
+		return u, nil
+	},
+	Multiaddr: func(v string) (any, error) {
+		return multiaddr.NewMultiaddr(v)
+	},
+	CID: func(v string) (any, error) {
+		return cid.Decode(v)
+	},
+	KeyValue: func(v string) (any, error) {
+		key, val, ok := strings.Cut(v, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%q is not a key=value pair", v)
+		}
+		return map[string]string{key: val}, nil
+	},
 }
 
 func (o *option) Parse(v string) (any, error) {
 	conv, ok := converters[o.Type()]
 	if !ok {
-		return nil, fmt.Errorf("option %q takes %s arguments, but was passed %q", o.Name(), o.Type(), v)
+		return nil, fmt.Errorf("option %q takes %s arguments, but was passed %q", o.Name(), OptionTypeName(o.Type()), v)
 	}
 
 	return conv(v)
@@ -149,10 +294,25 @@ func NewOption(kind reflect.Kind, names ...string) Option {
 }
 
 func (o *option) WithDefault(v any) Option {
+	o.checkDefault(v)
+	o.defaultVal = v
+	return o
+}
+
+// checkDefault panics if v is not a valid default of the option.
+func (o *option) checkDefault(v any) {
 	if v == nil {
 		panic(fmt.Errorf("cannot use nil as a default"))
 	}
 
+	// values of rich types must have their exact Go type
+	if typ := OptionGoType(o.Type()); typ != nil {
+		if vType := reflect.TypeOf(v); vType != typ {
+			panic(fmt.Errorf("invalid default for the given type, expected %s got %s", typ, vType))
+		}
+		return
+	}
+
 	// if type of value does not match the option type
 	if vKind, oKind := reflect.TypeOf(v).Kind(), o.Type(); vKind != oKind {
 		// if the reason they do not match is not because of Slice vs Array equivalence
@@ -161,14 +321,97 @@ func (o *option) WithDefault(v any) Option {
 			panic(fmt.Errorf("invalid default for the given type, expected %s got %s", o.Type(), vKind))
 		}
 	}
-	o.defaultVal = v
-	return o
 }
 
 func (o *option) Default() any {
 	return o.defaultVal
 }
 
+// WithVersionDefault sets the default of requests to the given API version
+// and later ones, up to the next version with its own default.
+func (o *option) WithVersionDefault(version int, v any) Option {
+	o.checkDefault(v)
+	if o.versionDefaults == nil {
+		o.versionDefaults = make(map[int]any)
+	}
+	o.versionDefaults[version] = v
+	return o
+}
+
+// VersionDefault returns the default of requests to the given API version:
+// the default of the latest version up to it that changed the default, or
+// Default.
+func (o *option) VersionDefault(version int) any {
+	latest, dflt := -1, o.defaultVal
+	for v, d := range o.versionDefaults {
+		if v <= version && v > latest {
+			latest, dflt = v, d
+		}
+	}
+	return dflt
+}
+
+func (o *option) WithAllowed(values ...any) Option {
+	o.constraints.Allowed = values
+	return o
+}
+
+func (o *option) WithMin(min float64) Option {
+	o.checkNumeric()
+	o.constraints.Min = &min
+	return o
+}
+
+func (o *option) WithMax(max float64) Option {
+	o.checkNumeric()
+	o.constraints.Max = &max
+	return o
+}
+
+func (o *option) checkNumeric() {
+	switch o.kind {
+	case Int, Uint, Int64, Uint64, Float:
+	default:
+		panic(fmt.Errorf("cannot set the range of an option of type %s", o.kind))
+	}
+}
+
+// WithPattern panics if expr is not a valid regular expression.
+func (o *option) WithPattern(expr string) Option {
+	if o.kind != String && o.kind != Strings {
+		panic(fmt.Errorf("cannot set the pattern of an option of type %s", o.kind))
+	}
+	o.constraints.Pattern = regexp.MustCompile(expr)
+	return o
+}
+
+func (o *option) WithRequired() Option {
+	o.constraints.Required = true
+	return o
+}
+
+func (o *option) Constraints() Constraints {
+	return o.constraints
+}
+
+func (o *option) WithEnv(vars ...string) Option {
+	o.env = vars
+	return o
+}
+
+func (o *option) Env() []string {
+	return o.env
+}
+
+func (o *option) WithDeprecation(d Deprecation) Option {
+	o.deprecation = &d
+	return o
+}
+
+func (o *option) Deprecation() *Deprecation {
+	return o.deprecation
+}
+
 // TODO handle description separately. this will take care of the panic case in
 // NewOption
 
@@ -197,10 +440,50 @@ func StringOption(names ...string) Option {
 	return NewOption(String, names...)
 }
 
+// DurationOption is a command option that takes a time.Duration, parsed
+// with time.ParseDuration.
+func DurationOption(names ...string) Option {
+	return NewOption(Duration, names...)
+}
+
+// ByteSizeOption is a command option that takes a number of bytes as an
+// uint64, parsed with ParseByteSize.
+func ByteSizeOption(names ...string) Option {
+	return NewOption(ByteSize, names...)
+}
+
+// URLOption is a command option that takes an absolute URL as a *url.URL.
+func URLOption(names ...string) Option {
+	return NewOption(URL, names...)
+}
+
+// MultiaddrOption is a command option that takes a multiaddr.Multiaddr.
+func MultiaddrOption(names ...string) Option {
+	return NewOption(Multiaddr, names...)
+}
+
+// CIDOption is a command option that takes a cid.Cid.
+func CIDOption(names ...string) Option {
+	return NewOption(CID, names...)
+}
+
+// KeyValueOption is a command option that takes key=value pairs, collected
+// in a map[string]string. It can be passed several times, e.g.
+// `command --header=a=1 --header=b=2`.
+func KeyValueOption(names ...string) Option {
+	return NewOption(KeyValue, names...)
+}
+
+// TristateOption is a flag that is unset unless enabled with --flag or
+// disabled with --no-flag. Its values are TriBool.
+func TristateOption(names ...string) Option {
+	return NewOption(Tristate, names...)
+}
+
 // StringsOption is a command option that can handle a slice of strings
 func StringsOption(names ...string) Option {
 	return &stringsOption{
-		Option:    NewOption(Strings, names...),
+		option:    NewOption(Strings, names...).(*option),
 		delimiter: "",
 	}
 }
@@ -216,23 +499,63 @@ func DelimitedStringsOption(delimiter string, names ...string) Option {
 		panic("cannot create a DelimitedStringsOption with no delimiter")
 	}
 	return &stringsOption{
-		Option:    NewOption(Strings, names...),
+		option:    NewOption(Strings, names...).(*option),
 		delimiter: delimiter,
 	}
 }
 
 type stringsOption struct {
-	Option
+	*option
 	delimiter string
 }
 
 func (s *stringsOption) WithDefault(v any) Option {
 	if v == nil {
-		return s.Option.WithDefault(v)
+		return s.option.WithDefault(v)
 	}
 
 	defVal := v.([]string)
-	s.Option = s.Option.WithDefault(defVal)
+	s.option.WithDefault(defVal)
+	return s
+}
+
+func (s *stringsOption) WithAllowed(values ...any) Option {
+	s.option.WithAllowed(values...)
+	return s
+}
+
+func (s *stringsOption) WithMin(min float64) Option {
+	s.option.WithMin(min)
+	return s
+}
+
+func (s *stringsOption) WithMax(max float64) Option {
+	s.option.WithMax(max)
+	return s
+}
+
+func (s *stringsOption) WithPattern(expr string) Option {
+	s.option.WithPattern(expr)
+	return s
+}
+
+func (s *stringsOption) WithRequired() Option {
+	s.option.WithRequired()
+	return s
+}
+
+func (s *stringsOption) WithVersionDefault(version int, v any) Option {
+	s.option.WithVersionDefault(version, v)
+	return s
+}
+
+func (s *stringsOption) WithEnv(vars ...string) Option {
+	s.option.WithEnv(vars...)
+	return s
+}
+
+func (s *stringsOption) WithDeprecation(d Deprecation) Option {
+	s.option.WithDeprecation(d)
 	return s
 }
 
@@ -1,10 +1,16 @@
 package cmds
 
 import (
+	"context"
 	"math"
+	"net/url"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multiaddr"
 )
 
 func TestLackOfDescriptionOfOptionDoesNotPanic(t *testing.T) {
@@ -96,3 +102,175 @@ func TestDescription(t *testing.T) {
 		}
 	}
 }
+
+func TestParseRichTypes(t *testing.T) {
+	u, _ := url.Parse("https://example.com/a?b=c")
+	ma, _ := multiaddr.NewMultiaddr("/ip4/127.0.0.1/tcp/5001")
+	c, _ := cid.Decode("bafkqaaa")
+
+	tcs := []struct {
+		opt Option
+		str string
+		v   any
+		err bool
+	}{
+		{opt: DurationOption("d"), str: "1m30s", v: 90 * time.Second},
+		{opt: DurationOption("d"), str: "90", err: true},
+		{opt: ByteSizeOption("s"), str: "256KiB", v: uint64(256 << 10)},
+		{opt: ByteSizeOption("s"), str: "1GB", v: uint64(1e9)},
+		{opt: ByteSizeOption("s"), str: "lots", err: true},
+		{opt: URLOption("u"), str: "https://example.com/a?b=c", v: u},
+		{opt: URLOption("u"), str: "example.com", err: true},
+		{opt: MultiaddrOption("m"), str: "/ip4/127.0.0.1/tcp/5001", v: ma},
+		{opt: MultiaddrOption("m"), str: "127.0.0.1:5001", err: true},
+		{opt: CIDOption("c"), str: "bafkqaaa", v: c},
+		{opt: CIDOption("c"), str: "notacid", err: true},
+		{opt: KeyValueOption("kv"), str: "a=b=c", v: map[string]string{"a": "b=c"}},
+		{opt: KeyValueOption("kv"), str: "a", err: true},
+		{opt: TristateOption("t"), str: "", v: True},
+		{opt: TristateOption("t"), str: "FALSE", v: False},
+		{opt: TristateOption("t"), str: "maybe", err: true},
+	}
+
+	for _, tc := range tcs {
+		v, err := tc.opt.Parse(tc.str)
+		if (err != nil) != tc.err {
+			t.Errorf("%s %q: unexpected error %v", OptionTypeName(tc.opt.Type()), tc.str, err)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(v, tc.v) {
+			t.Errorf("%s %q: expected %v but got %v", OptionTypeName(tc.opt.Type()), tc.str, tc.v, v)
+		}
+	}
+}
+
+func TestRichTypeDefaults(t *testing.T) {
+	opt := DurationOption("d", "A duration").WithDefault(time.Minute)
+	if desc := opt.Description(); desc != "A duration. Default: 1m0s." {
+		t.Fatalf("unexpected description %q", desc)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic for a default of the wrong type")
+		}
+	}()
+	ByteSizeOption("s").WithDefault(1024)
+}
+
+func TestRichTypeRequest(t *testing.T) {
+	root := &Command{
+		Options: []Option{
+			DurationOption("timeout"),
+			KeyValueOption("header"),
+		},
+	}
+
+	req, err := NewRequest(context.Background(), nil, OptMap{
+		"timeout": "10s",
+		"header":  []string{"a=1", "b=2"},
+	}, nil, nil, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Options["timeout"] != 10*time.Second {
+		t.Errorf("unexpected timeout %v", req.Options["timeout"])
+	}
+	if !reflect.DeepEqual(req.Options["header"], map[string]string{"a": "1", "b": "2"}) {
+		t.Errorf("unexpected headers %v", req.Options["header"])
+	}
+
+	_, err = NewRequest(context.Background(), nil, OptMap{"timeout": 10}, nil, nil, root)
+	if err == nil {
+		t.Fatal("expected an error for a value of the wrong type")
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	root := &Command{Options: []Option{OptionTimeout}}
+
+	req, err := NewRequest(context.Background(), nil, OptMap{TimeoutOpt: "1m"}, nil, nil, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timeout, ok, err := req.Timeout(); err != nil || !ok || timeout != time.Minute {
+		t.Fatalf("expected a minute, got %v, %v, %v", timeout, ok, err)
+	}
+
+	// strings set on the request directly are still parsed
+	req.Options[TimeoutOpt] = "2s"
+	if timeout, ok, err := req.Timeout(); err != nil || !ok || timeout != 2*time.Second {
+		t.Fatalf("expected two seconds, got %v, %v, %v", timeout, ok, err)
+	}
+	req.Options[TimeoutOpt] = "soon"
+	if _, _, err := req.Timeout(); err == nil {
+		t.Fatal("expected an error for an invalid timeout")
+	}
+
+	delete(req.Options, TimeoutOpt)
+	if _, ok, err := req.Timeout(); ok || err != nil {
+		t.Fatalf("expected no timeout, got %v, %v", ok, err)
+	}
+}
+
+func TestTristateOption(t *testing.T) {
+	root := &Command{
+		Options: []Option{
+			TristateOption("quiet", "q", "Be quiet."),
+			TristateOption("pin", "Pin the content."),
+		},
+	}
+
+	req, err := NewRequest(context.Background(), nil, OptMap{"quiet": false, "pin": "true"}, nil, nil, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Options["quiet"] != False || req.Options["pin"] != True {
+		t.Fatalf("unexpected options %v", req.Options)
+	}
+
+	req, err = NewRequest(context.Background(), nil, OptMap{}, nil, nil, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	quiet, _ := req.Options["quiet"].(TriBool)
+	if quiet != Unset || quiet.IsSet() || !quiet.Bool(true) || quiet.String() != "unset" {
+		t.Fatalf("expected an unset option, got %v", quiet)
+	}
+	if !True.Bool(false) || False.Bool(true) {
+		t.Fatal("set options should ignore the fallback")
+	}
+}
+
+func TestVersionDefault(t *testing.T) {
+	pin := WithVersionDefault(BoolOption("pin", "Pin the content.").WithDefault(true), 2, false)
+	for version, exp := range map[int]bool{0: true, 1: true, 2: false, 5: false} {
+		if got := OptionVersionDefault(pin, version); got != exp {
+			t.Errorf("version %d: expected default %v, got %v", version, exp, got)
+		}
+	}
+
+	tags := WithVersionDefault(StringsOption("tag", "Tags to add."), 1, []string{"a"})
+	if got := OptionVersionDefault(tags, 0); got != nil {
+		t.Errorf("expected no default before version 1, got %v", got)
+	}
+
+	root := &Command{Options: []Option{pin, tags}}
+	req, err := NewRequest(context.Background(), nil, OptMap{}, nil, nil, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.APIVersion = 2
+	if err := req.FillDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	if req.Options["pin"] != false || !reflect.DeepEqual(req.Options["tag"], []string{"a"}) {
+		t.Errorf("expected the defaults of version 2, got %v", req.Options)
+	}
+
+	// options without version defaults always use their default
+	plain := plainOption{BoolOption("pin", "").WithDefault(true)}
+	if got := OptionVersionDefault(plain, 2); got != true {
+		t.Errorf("expected the default, got %v", got)
+	}
+}
@@ -2,31 +2,43 @@ package cmds
 
 // Flag names
 const (
-	EncShort      = "enc"
-	EncLong       = "encoding"
-	RecShort      = "r"
-	RecLong       = "recursive"
-	ChanOpt       = "stream-channels"
-	TimeoutOpt    = "timeout"
-	OptShortHelp  = "h"
-	OptLongHelp   = "help"
-	DerefLong     = "dereference-args"
-	DerefSymlinks = "dereference-symlinks"
-	StdinName     = "stdin-name"
-	Hidden        = "hidden"
-	HiddenShort   = "H"
-	Ignore        = "ignore"
-	IgnoreRules   = "ignore-rules-path"
+	EncShort       = "enc"
+	EncLong        = "encoding"
+	RecShort       = "r"
+	RecLong        = "recursive"
+	ChanOpt        = "stream-channels"
+	TimeoutOpt     = "timeout"
+	OptShortHelp   = "h"
+	OptLongHelp    = "help"
+	DerefLong      = "dereference-args"
+	DerefSymlinks  = "dereference-symlinks"
+	StdinName      = "stdin-name"
+	Hidden         = "hidden"
+	HiddenShort    = "H"
+	Ignore         = "ignore"
+	IgnoreRules    = "ignore-rules-path"
+	ColumnsOpt     = "columns"
+	FilterOpt      = "filter"
+	LastEventIDOpt = "last-event-id"
+	LimitOpt       = "limit"
+	CursorOpt      = "cursor"
+	ConfigFileOpt  = "config-file"
 )
 
 // options that are used by this package
-var OptionEncodingType = StringOption(EncLong, EncShort, "The encoding type the output should be encoded with (json, xml, or text)").WithDefault("text")
+var OptionEncodingType = StringOption(EncLong, EncShort, "The encoding type the output should be encoded with, e.g. json, yaml, cbor or text. Commands may not support all encodings").WithDefault("text")
 var OptionRecursivePath = BoolOption(RecLong, RecShort, "Add directory paths recursively")
 var OptionStreamChannels = BoolOption(ChanOpt, "Stream channel output")
-var OptionTimeout = StringOption(TimeoutOpt, "Set a global timeout on the command")
-var OptionDerefArgs = BoolOption(DerefLong, "DEPRECATED: use --dereference-symlinks instead. Only dereferences symlinks in CLI arguments, not inside directories.")
+var OptionTimeout = DurationOption(TimeoutOpt, "Set a global timeout on the command")
+var OptionDerefArgs = WithDeprecation(BoolOption(DerefLong, "Only dereferences symlinks in CLI arguments, not inside directories."), Deprecation{Note: "use --dereference-symlinks instead"})
 var OptionDerefSymlinks = BoolOption(DerefSymlinks, "Recursively resolve all symlinks to their target content. Works on symlinks inside directories, not just CLI arguments.")
 var OptionStdinName = StringOption(StdinName, "Assign a name if the file source is stdin.")
 var OptionHidden = BoolOption(Hidden, HiddenShort, "Include files that are hidden. Only takes effect on recursive add.")
 var OptionIgnore = StringsOption(Ignore, "A rule (.gitignore-stype) defining which file(s) should be ignored (variadic, experimental)")
+var OptionColumns = DelimitedStringsOption(",", ColumnsOpt, "Comma-separated list of columns to show in table and csv output")
+var OptionFilter = StringOption(FilterOpt, "A jq-style expression to filter the output with, e.g. '.Name' or '{Name, Size}'")
+var OptionLastEventID = StringOption(LastEventIDOpt, "Resume a stream of events after the event with this ID")
+var OptionLimit = IntOption(LimitOpt, "Maximum number of values to return")
+var OptionCursor = StringOption(CursorOpt, "Continue a listing from the cursor returned with a previous page")
+var OptionConfigFile = StringOption(ConfigFileOpt, "Path to a JSON, TOML or YAML file with option values")
 var OptionIgnoreRules = StringOption(IgnoreRules, "A path to a file with .gitignore-style ignore rules (experimental)")
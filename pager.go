@@ -0,0 +1,143 @@
+package cmds
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+)
+
+// ErrPageFull is returned by Pager.Emit when the page has reached its limit.
+// The value passed to Emit is not emitted, and marks that there is a next
+// page.
+var ErrPageFull = errors.New("page is full")
+
+// CursorSetter is implemented by response emitters that send the cursor of
+// the next page out of band, e.g. in a header.
+type CursorSetter interface {
+	SetNextCursor(cursor string)
+}
+
+// CursorResponse is implemented by responses that received the cursor of
+// the next page out of band. NextCursor returns "" if there is no next page
+// or the response has not been read completely.
+type CursorResponse interface {
+	NextCursor() string
+}
+
+// Cursor is emitted as the final value of a page by emitters that can't
+// send the cursor of the next page out of band.
+type Cursor struct {
+	Next string
+}
+
+// SetNextCursor sends the cursor of the next page to the client of re.
+func SetNextCursor(re ResponseEmitter, cursor string) error {
+	if cs, ok := re.(CursorSetter); ok {
+		cs.SetNextCursor(cursor)
+		return nil
+	}
+	return re.Emit(&Cursor{Next: cursor})
+}
+
+// Pager emits a page of the values of a list command, as selected by the
+// LimitOpt and CursorOpt options. The values must be listed in a stable
+// order, starting at Offset:
+//
+//	p, err := cmds.NewPager(req, re)
+//	if err != nil {
+//		return err
+//	}
+//	for _, v := range list[min(p.Offset(), len(list)):] {
+//		if err := p.Emit(v); err != nil {
+//			if err == cmds.ErrPageFull {
+//				break
+//			}
+//			return err
+//		}
+//	}
+//	return p.Finish()
+type Pager struct {
+	re ResponseEmitter
+
+	limit  int
+	offset int
+	n      int
+	full   bool
+}
+
+// NewPager returns a Pager for the page requested by req. An invalid limit
+// or cursor is a client error.
+func NewPager(req *Request, re ResponseEmitter) (*Pager, error) {
+	p := &Pager{re: re}
+
+	if limit, ok := req.Options[LimitOpt].(int); ok {
+		if limit < 0 {
+			return nil, Errorf(ErrClient, "invalid limit %d", limit)
+		}
+		p.limit = limit
+	}
+
+	if cursor, _ := req.Options[CursorOpt].(string); cursor != "" {
+		offset, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, Errorf(ErrClient, "invalid cursor %q", cursor)
+		}
+		p.offset = offset
+	}
+
+	return p, nil
+}
+
+// Offset returns the number of values to skip, i.e. the position of the
+// first value of the page.
+func (p *Pager) Offset() int {
+	return p.offset
+}
+
+// Limit returns the maximum number of values of the page, or 0 if it is not
+// limited.
+func (p *Pager) Limit() int {
+	return p.limit
+}
+
+// Emit emits v, unless the page is full, in which case it returns
+// ErrPageFull.
+func (p *Pager) Emit(v any) error {
+	if p.limit > 0 && p.n >= p.limit {
+		p.full = true
+		return ErrPageFull
+	}
+
+	if err := p.re.Emit(v); err != nil {
+		return err
+	}
+	p.n++
+	return nil
+}
+
+// Finish sends the cursor of the next page, if there is one.
+func (p *Pager) Finish() error {
+	if !p.full {
+		return nil
+	}
+	return SetNextCursor(p.re, encodeCursor(p.offset+p.n))
+}
+
+// cursors are opaque to clients, so the encoding can change without
+// breaking them.
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+
+	offset, err := strconv.Atoi(string(data))
+	if err == nil && offset < 0 {
+		err = errors.New("negative offset")
+	}
+	return offset, err
+}
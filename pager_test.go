@@ -0,0 +1,110 @@
+package cmds
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func listPage(t *testing.T, opts OptMap, list []int) ([]any, string) {
+	t.Helper()
+
+	req, err := NewRequest(context.Background(), nil, opts, nil, nil, &Command{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	re, res := NewChanResponsePair(req)
+	go func() {
+		p, err := NewPager(req, re)
+		if err != nil {
+			re.CloseWithError(err)
+			return
+		}
+
+		for _, v := range list[min(p.Offset(), len(list)):] {
+			if err := p.Emit(v); err != nil {
+				if err == ErrPageFull {
+					break
+				}
+				re.CloseWithError(err)
+				return
+			}
+		}
+		re.CloseWithError(p.Finish())
+	}()
+
+	var values []any
+	for {
+		v, err := res.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, v)
+	}
+	return values, res.(CursorResponse).NextCursor()
+}
+
+func TestPager(t *testing.T) {
+	list := []int{0, 1, 2, 3, 4}
+
+	values, cursor := listPage(t, OptMap{LimitOpt: 2}, list)
+	if !reflect.DeepEqual(values, []any{0, 1}) || cursor == "" {
+		t.Fatalf("unexpected first page %v, cursor %q", values, cursor)
+	}
+
+	values, cursor = listPage(t, OptMap{LimitOpt: 2, CursorOpt: cursor}, list)
+	if !reflect.DeepEqual(values, []any{2, 3}) || cursor == "" {
+		t.Fatalf("unexpected second page %v, cursor %q", values, cursor)
+	}
+
+	// the last page has no cursor, even if it is full
+	values, cursor = listPage(t, OptMap{LimitOpt: 1, CursorOpt: cursor}, list)
+	if !reflect.DeepEqual(values, []any{4}) || cursor != "" {
+		t.Fatalf("unexpected last page %v, cursor %q", values, cursor)
+	}
+
+	values, cursor = listPage(t, OptMap{}, list)
+	if len(values) != len(list) || cursor != "" {
+		t.Fatalf("unexpected unlimited page %v, cursor %q", values, cursor)
+	}
+}
+
+func TestPagerInvalid(t *testing.T) {
+	for _, opts := range []OptMap{
+		{LimitOpt: -1},
+		{CursorOpt: "not a cursor!"},
+		{CursorOpt: encodeCursor(-5)},
+	} {
+		req := &Request{Options: opts}
+		_, err := NewPager(req, nil)
+		if e, ok := err.(Error); !ok || e.Code != ErrClient {
+			t.Errorf("%v: expected client error, got %v", opts, err)
+		}
+	}
+}
+
+func TestSetNextCursorFrame(t *testing.T) {
+	req, err := NewRequest(context.Background(), nil, OptMap{EncLong: JSON}, nil, nil, &Command{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	re, err := NewWriterResponseEmitter(writecloser{&buf, nopCloser{}}, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetNextCursor(re, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if exp := "{\"Next\":\"abc\"}\n"; buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+}
@@ -0,0 +1,85 @@
+package cmds
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// ProgressEvent is the name of the events progress reports are sent as in
+// an event stream.
+const ProgressEvent = "progress"
+
+// Progress reports the progress of a long-running command. Progress is
+// reported separately from the values a command emits, so it doesn't need
+// to be part of the command's Type.
+type Progress struct {
+	// Done is the amount of work done so far, in units of the command's
+	// choosing, e.g. bytes or blocks.
+	Done uint64
+
+	// Total is the total amount of work, or 0 if it is unknown.
+	Total uint64
+
+	// Phase names the current step of the command, if it has several.
+	Phase string `json:",omitempty"`
+
+	// Message describes the work currently done, e.g. a file name.
+	Message string `json:",omitempty"`
+}
+
+// progressFrameKey is the only key of the JSON objects progress reports are
+// wrapped in when they are sent between values. It isn't a valid Go
+// identifier, so encoded structs can't be mistaken for progress reports.
+const progressFrameKey = "$progress"
+
+// ProgressFrame wraps a progress report sent in a stream of JSON values, so
+// that it can't be confused with the values. MaybeError decodes frames into
+// its Progress field.
+type ProgressFrame struct {
+	Progress Progress `json:"$progress"`
+}
+
+// isProgressFrame reports whether data is the JSON encoding of a
+// ProgressFrame, i.e. an object with the single key progressFrameKey.
+func isProgressFrame(data []byte) bool {
+	// avoid decoding values that can't be frames
+	if !bytes.Contains(data, []byte(`"`+progressFrameKey+`"`)) {
+		return false
+	}
+
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(data, &obj) != nil || len(obj) != 1 {
+		return false
+	}
+	_, ok := obj[progressFrameKey]
+	return ok
+}
+
+// ProgressEmitter is implemented by response emitters that can report
+// progress.
+type ProgressEmitter interface {
+	EmitProgress(Progress) error
+}
+
+// ProgressResponse is implemented by responses that receive progress
+// reports alongside their values.
+type ProgressResponse interface {
+	// SetProgressHandler sets the function that progress reports are passed
+	// to while reading the response. Without a handler, they are dropped.
+	SetProgressHandler(func(Progress))
+}
+
+// EmitProgress reports progress to the client of re. The report is dropped
+// if re can't report progress.
+func EmitProgress(re ResponseEmitter, p Progress) error {
+	if pe, ok := re.(ProgressEmitter); ok {
+		return pe.EmitProgress(p)
+	}
+	return nil
+}
+
+// progressReport carries progress reports in the stream of values of a
+// channel response pair.
+type progressReport struct {
+	Progress
+}
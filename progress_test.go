@@ -0,0 +1,115 @@
+package cmds
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestProgressChanResponse(t *testing.T) {
+	req, err := NewRequest(context.Background(), nil, nil, nil, nil, &Command{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	re, res := NewChanResponsePair(req)
+	go func() {
+		re.Emit("a")
+		EmitProgress(re, Progress{Done: 1, Total: 2, Phase: "test"})
+		EmitProgress(re, Progress{Done: 2, Total: 2})
+		re.Emit("b")
+		re.Close()
+	}()
+
+	var reports []Progress
+	var values []any
+	for {
+		v, err := res.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, v)
+
+		// reports are read on the next call of Next
+		res.(ProgressResponse).SetProgressHandler(func(p Progress) {
+			reports = append(reports, p)
+		})
+	}
+
+	if !reflect.DeepEqual(values, []any{"a", "b"}) {
+		t.Fatalf("unexpected values %v", values)
+	}
+	exp := []Progress{{Done: 1, Total: 2, Phase: "test"}, {Done: 2, Total: 2}}
+	if !reflect.DeepEqual(reports, exp) {
+		t.Fatalf("expected reports %v, got %v", exp, reports)
+	}
+}
+
+func TestProgressMaybeError(t *testing.T) {
+	data, err := json.Marshal(ProgressFrame{Progress{Done: 3, Total: 10, Message: "hi"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := `{"$progress":{"Done":3,"Total":10,"Message":"hi"}}`; string(data) != exp {
+		t.Fatalf("expected %s, got %s", exp, data)
+	}
+
+	m := &MaybeError{Value: &Foo{}}
+	if err := json.Unmarshal(data, m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Progress == nil || *m.Progress != (Progress{Done: 3, Total: 10, Message: "hi"}) {
+		t.Fatalf("expected progress, got %#v", m)
+	}
+
+	// values that look like progress are still values
+	for _, data := range []string{
+		`{"Bar":4}`,
+		`{"Done":1,"Total":2,"Type":"progress"}`,
+		`{"$progress":{"Done":1},"Bar":4}`,
+	} {
+		m = &MaybeError{}
+		if err := json.Unmarshal([]byte(data), m); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.Get(); err != nil || m.Progress != nil {
+			t.Fatalf("%s: expected a value, got %#v", data, m)
+		}
+	}
+}
+
+type progressEmitter struct {
+	*testEmitter
+	reports []Progress
+}
+
+func (re *progressEmitter) EmitProgress(p Progress) error {
+	re.reports = append(re.reports, p)
+	return nil
+}
+
+func TestCopyProgress(t *testing.T) {
+	req, err := NewRequest(context.Background(), nil, nil, nil, nil, &Command{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	re, res := NewChanResponsePair(req)
+	go func() {
+		EmitProgress(re, Progress{Done: 1})
+		re.Close()
+	}()
+
+	dst := &progressEmitter{testEmitter: newTestEmitter(t)}
+	if err := Copy(dst, res); err != nil {
+		t.Fatal(err)
+	}
+	if len(dst.reports) != 1 || dst.reports[0].Done != 1 {
+		t.Fatalf("expected progress to be forwarded, got %v", dst.reports)
+	}
+}
@@ -0,0 +1,80 @@
+package cmds
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+)
+
+// ProtobufEncoder encodes values implementing proto.Message. Every message
+// is prefixed with its varint-encoded length, so that a stream of messages
+// can be split again by the receiver.
+type ProtobufEncoder struct {
+	w io.Writer
+}
+
+// NewProtobufEncoder returns an encoder writing length-delimited protobuf
+// messages to w.
+func NewProtobufEncoder(w io.Writer) *ProtobufEncoder {
+	return &ProtobufEncoder{w: w}
+}
+
+func (e *ProtobufEncoder) Encode(v any) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("cannot encode %T as protobuf: value does not implement proto.Message", v)
+	}
+
+	_, err := protodelim.MarshalTo(e.w, msg)
+	return err
+}
+
+// ProtobufDecoder decodes length-delimited protobuf messages.
+//
+// Protobuf messages are not self-describing, so the value passed to Decode
+// must implement proto.Message. When passed a *MaybeError, the message is
+// decoded into a new value of the type of MaybeError.Value, which usually is
+// the Command's Type.
+type ProtobufDecoder struct {
+	r *bufio.Reader
+}
+
+// NewProtobufDecoder returns a decoder reading length-delimited protobuf
+// messages from r.
+func NewProtobufDecoder(r io.Reader) *ProtobufDecoder {
+	return &ProtobufDecoder{r: bufio.NewReader(r)}
+}
+
+func (d *ProtobufDecoder) Decode(v any) error {
+	m, isMaybeError := v.(*MaybeError)
+	if isMaybeError {
+		if m.Value == nil {
+			return fmt.Errorf("cannot decode protobuf without a value type")
+		}
+
+		// never decode into the Command's Type value itself
+		t := reflect.TypeOf(m.Value)
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		v = reflect.New(t).Interface()
+	}
+
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("cannot decode protobuf into %T: value does not implement proto.Message", v)
+	}
+
+	if err := protodelim.UnmarshalFrom(d.r, msg); err != nil {
+		return err
+	}
+
+	if isMaybeError {
+		m.Value = msg
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package cmds
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestProtobufRoundTrip(t *testing.T) {
+	buf := new(bytes.Buffer)
+	enc := NewProtobufEncoder(buf)
+
+	for _, s := range []string{"foo", "bar"} {
+		if err := enc.Encode(wrapperspb.String(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := enc.Encode(Foo{}); err == nil {
+		t.Fatal("expected error encoding a non-protobuf value")
+	}
+
+	typ := &wrapperspb.StringValue{}
+	dec := NewProtobufDecoder(buf)
+	for _, exp := range []string{"foo", "bar"} {
+		m := &MaybeError{Value: typ}
+		if err := dec.Decode(m); err != nil {
+			t.Fatal(err)
+		}
+		v, err := m.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.(*wrapperspb.StringValue).GetValue() != exp {
+			t.Fatalf("expected %q, got %v", exp, v)
+		}
+	}
+
+	if typ.GetValue() != "" {
+		t.Fatal("decoded into the type value")
+	}
+
+	if err := dec.Decode(&MaybeError{Value: typ}); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func TestProtobufDecodeWithoutType(t *testing.T) {
+	dec := NewProtobufDecoder(bytes.NewReader([]byte{0}))
+	if err := dec.Decode(&MaybeError{}); err == nil {
+		t.Fatal("expected error decoding without a value type")
+	}
+}
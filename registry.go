@@ -0,0 +1,145 @@
+package cmds
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Registry holds a command tree that can be changed while it is being
+// served, e.g. when a plugin loads. Changes never modify the commands of the
+// tree: they build a new tree that shares the unchanged subtrees with the old
+// one, and then swap the root. Requests that resolved their commands before
+// a change keep using the old ones.
+//
+// Commands must not be modified after they have been registered.
+type Registry struct {
+	// mu serializes changes
+	mu   sync.Mutex
+	root atomic.Pointer[Command]
+
+	version  uint64
+	watchers map[int]func(RegistryEvent)
+	nextID   int
+}
+
+// RegistryEvent describes a change of the command tree of a Registry.
+// Watchers should drop anything they derived from the old tree, such as
+// help texts, schemas or completions.
+type RegistryEvent struct {
+	// Path is the path of the subtree that was registered or removed.
+	Path []string
+	// Root is the root of the tree after the change.
+	Root *Command
+	// Version is incremented with every change, so watchers can tell
+	// events apart that are delivered out of order.
+	Version uint64
+}
+
+// NewRegistry returns a registry serving the tree of root.
+func NewRegistry(root *Command) *Registry {
+	r := &Registry{watchers: make(map[int]func(RegistryEvent))}
+	r.root.Store(root)
+	return r
+}
+
+// Root returns the current root command. It is safe to use while the
+// registry is changed.
+func (r *Registry) Root() *Command {
+	return r.root.Load()
+}
+
+// Register adds cmd at path, replacing the subtree at that path if there is
+// one. The parent of path must exist. An empty path replaces the root.
+func (r *Registry) Register(path []string, cmd *Command) error {
+	if cmd == nil {
+		return fmt.Errorf("cannot register a nil command")
+	}
+	return r.update(path, cmd)
+}
+
+// Unregister removes the subtree at path.
+func (r *Registry) Unregister(path []string) error {
+	if len(path) == 0 {
+		return fmt.Errorf("cannot unregister the root command")
+	}
+	return r.update(path, nil)
+}
+
+// Watch calls fn after every change of the tree, until the returned
+// function is called. fn is called from the goroutine that made the change,
+// and must not block.
+func (r *Registry) Watch(fn func(RegistryEvent)) (cancel func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := r.nextID
+	r.nextID++
+	r.watchers[id] = fn
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.watchers, id)
+	}
+}
+
+func (r *Registry) update(path []string, cmd *Command) error {
+	r.mu.Lock()
+
+	root := cmd
+	if len(path) > 0 {
+		var err error
+		root, err = withSubcommand(r.root.Load(), path, cmd)
+		if err != nil {
+			r.mu.Unlock()
+			return err
+		}
+	}
+
+	r.root.Store(root)
+	r.version++
+	ev := RegistryEvent{Path: slices.Clone(path), Root: root, Version: r.version}
+	watchers := slices.Collect(maps.Values(r.watchers))
+	r.mu.Unlock()
+
+	for _, fn := range watchers {
+		fn(ev)
+	}
+	return nil
+}
+
+// withSubcommand returns a copy of c in which the subcommand at path is set
+// to sub, or removed if sub is nil. Only the commands along path are copied.
+func withSubcommand(c *Command, path []string, sub *Command) (*Command, error) {
+	name, next := c.Subcommand(path[0])
+	if next == nil {
+		if len(path) > 1 || sub == nil {
+			return nil, fmt.Errorf("undefined command: %q", strings.Join(path, "/"))
+		}
+		name = path[0]
+	}
+
+	cp := *c
+	cp.Subcommands = maps.Clone(c.Subcommands)
+	if cp.Subcommands == nil {
+		cp.Subcommands = make(map[string]*Command)
+	}
+
+	switch {
+	case len(path) > 1:
+		nextCp, err := withSubcommand(next, path[1:], sub)
+		if err != nil {
+			return nil, fmt.Errorf("undefined command: %q", strings.Join(path, "/"))
+		}
+		cp.Subcommands[name] = nextCp
+	case sub == nil:
+		delete(cp.Subcommands, name)
+	default:
+		cp.Subcommands[name] = sub
+	}
+	return &cp, nil
+}
@@ -0,0 +1,104 @@
+package cmds
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	ls := &Command{}
+	root := &Command{
+		Subcommands: map[string]*Command{
+			"ls": ls,
+			"pin": {
+				Aliases:     []string{"p"},
+				Subcommands: map[string]*Command{"add": {}},
+			},
+		},
+	}
+	reg := NewRegistry(root)
+
+	var events []RegistryEvent
+	cancel := reg.Watch(func(ev RegistryEvent) {
+		events = append(events, ev)
+	})
+
+	rm := &Command{}
+	if err := reg.Register([]string{"p", "rm"}, rm); err != nil {
+		t.Fatal(err)
+	}
+	before := reg.Root()
+	if got, err := before.Get([]string{"pin", "rm"}); err != nil || got != rm {
+		t.Fatalf("expected the registered command, got %v, %v", got, err)
+	}
+	if before.Subcommands["ls"] != ls {
+		t.Error("unchanged subtrees should be shared")
+	}
+	if _, err := root.Get([]string{"pin", "rm"}); err == nil {
+		t.Error("the old tree must not change")
+	}
+
+	if err := reg.Unregister([]string{"ls"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reg.Root().Get([]string{"ls"}); err == nil {
+		t.Error("expected ls to be removed")
+	}
+	if got, _ := before.Get([]string{"ls"}); got != ls {
+		t.Error("snapshots must keep their commands")
+	}
+
+	for _, path := range [][]string{{"ls"}, {"nope", "add"}, {"pin", "nope", "x"}} {
+		if err := reg.Unregister(path); err == nil {
+			t.Errorf("expected an error unregistering %v", path)
+		}
+	}
+	if err := reg.Unregister(nil); err == nil {
+		t.Error("expected an error unregistering the root")
+	}
+
+	cancel()
+	newRoot := &Command{}
+	if err := reg.Register(nil, newRoot); err != nil || reg.Root() != newRoot {
+		t.Fatalf("expected the root to be replaced, got %v", err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if ev := events[0]; !slices.Equal(ev.Path, []string{"p", "rm"}) || ev.Root != before || ev.Version != 1 {
+		t.Errorf("unexpected event %+v", ev)
+	}
+	if ev := events[1]; !slices.Equal(ev.Path, []string{"ls"}) || ev.Version != 2 {
+		t.Errorf("unexpected event %+v", ev)
+	}
+}
+
+func TestRegistryConcurrent(t *testing.T) {
+	reg := NewRegistry(&Command{})
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			name := fmt.Sprint("cmd", i)
+			for range 100 {
+				if err := reg.Register([]string{name}, &Command{}); err != nil {
+					t.Error(err)
+				}
+				if _, err := reg.Root().Get([]string{name}); err != nil {
+					t.Error(err)
+				}
+				if err := reg.Unregister([]string{name}); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	if n := len(reg.Root().Subcommands); n != 0 {
+		t.Errorf("expected no subcommands, got %d", n)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"maps"
 	"net/http"
 	"reflect"
+	"time"
 
 	"github.com/ipfs/boxo/files"
 )
@@ -33,6 +34,25 @@ type Request struct {
 	// correlation ids, trace context, feature flags.
 	Headers http.Header
 
+	// Config holds option values read from a config file. FillLocalDefaults
+	// uses them for options that are not set by the caller or the
+	// environment.
+	Config *Config
+
+	// Sources records where the value of every option filled in by
+	// FillDefaults came from, keyed like Options.
+	Sources map[string]OptionSource
+
+	// APIVersion is the API version the request was made to. FillDefaults
+	// uses the option defaults of that version. Local requests use
+	// version 0.
+	APIVersion int
+
+	// Warnings holds the deprecation warnings added by CheckDeprecations.
+	// The CLI prints them to stderr, the HTTP handler sends them in
+	// Warning headers.
+	Warnings []string
+
 	bodyArgs *arguments
 }
 
@@ -113,6 +133,26 @@ func (req *Request) SetOption(name string, value any) {
 	req.Options[name] = value
 }
 
+// Timeout returns the value of the timeout option, and whether it is set.
+// Strings, such as those set by callers from before the option was a
+// duration, are parsed with time.ParseDuration.
+func (req *Request) Timeout() (time.Duration, bool, error) {
+	switch v := req.Options[TimeoutOpt].(type) {
+	case nil:
+		return 0, false, nil
+	case time.Duration:
+		return v, true, nil
+	case string:
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, true, fmt.Errorf("invalid timeout %q: %s", v, err)
+		}
+		return timeout, true, nil
+	default:
+		return 0, true, fmt.Errorf("invalid timeout of type %T", v)
+	}
+}
+
 func checkAndConvertOptions(root *Command, opts OptMap, path []string) (OptMap, error) {
 	optDefs, err := root.GetOptions(path)
 	options := make(OptMap)
@@ -129,7 +169,13 @@ func checkAndConvertOptions(root *Command, opts OptMap, path []string) (OptMap,
 		}
 
 		kind := reflect.TypeOf(v).Kind()
-		if kind != opt.Type() {
+		if typ := OptionGoType(opt.Type()); typ != nil {
+			val, err := convertRichOption(opt, v, typ)
+			if err != nil {
+				return options, fmt.Errorf("%s (for option %q)", err, "-"+k)
+			}
+			options[k] = val
+		} else if kind != opt.Type() {
 			if opt.Type() == Strings {
 				if _, ok := v.([]string); !ok {
 					return options, fmt.Errorf("option %q should be type %q, but got type %q",
@@ -163,7 +209,45 @@ func checkAndConvertOptions(root *Command, opts OptMap, path []string) (OptMap,
 		}
 	}
 
-	return options, nil
+	// required options may still be set by FillDefaults
+	return options, checkOptions(root, path, options, nil, false)
+}
+
+// convertRichOption converts v to the Go type typ of the rich option opt.
+// Values are either of that type already, or strings to parse. Key-value
+// options also take lists of pairs, as sent over HTTP.
+func convertRichOption(opt Option, v any, typ reflect.Type) (any, error) {
+	if reflect.TypeOf(v) == typ {
+		return v, nil
+	}
+
+	switch v := v.(type) {
+	case string:
+		return opt.Parse(v)
+	case bool:
+		if opt.Type() != Tristate {
+			break
+		}
+		if v {
+			return True, nil
+		}
+		return False, nil
+	case []string:
+		if opt.Type() != KeyValue {
+			break
+		}
+		m := make(map[string]string, len(v))
+		for _, pair := range v {
+			kv, err := opt.Parse(pair)
+			if err != nil {
+				return nil, err
+			}
+			maps.Copy(m, kv.(map[string]string))
+		}
+		return m, nil
+	}
+
+	return nil, fmt.Errorf("expected a value of type %s, but got type %T", typ, v)
 }
 
 // GetEncoding returns the EncodingType set in a request, falling back to JSON
@@ -181,36 +265,59 @@ func GetEncoding(req *Request, def EncodingType) EncodingType {
 	}
 }
 
-// FillDefaults fills in default values if option has not been set.
+// FillDefaults fills in the values of the options that have not been set
+// with their defaults. Unlike FillLocalDefaults, it never reads the
+// environment or files, so it is safe for requests of remote callers. The
+// source of every value is recorded in Sources.
 func (req *Request) FillDefaults() error {
+	return req.fillDefaults(false)
+}
+
+// FillLocalDefaults fills in the values of the options that have not been
+// set. Values are taken from, in order: the environment variables of the
+// option, the config file and the default of the option. If the request has
+// no Config and the ConfigFileOpt option resolves to a path, the config file
+// is loaded from there. The source of every value is recorded in Sources.
+//
+// Only use it for requests of local users, such as those of the CLI.
+func (req *Request) FillLocalDefaults() error {
+	return req.fillDefaults(true)
+}
+
+func (req *Request) fillDefaults(local bool) error {
 	optDefMap, err := req.Root.GetOptions(req.Path)
 	if err != nil {
 		return err
 	}
 
+	if req.Sources == nil {
+		req.Sources = make(map[string]OptionSource)
+	}
+
+	// the config file may itself be set in any of the layers above it
+	if opt, ok := optDefMap[ConfigFileOpt]; ok && local && req.Config == nil {
+		if err := req.resolveOption(opt, local); err != nil {
+			return err
+		}
+		if path, _ := req.Options[opt.Name()].(string); path != "" {
+			cfg, err := LoadConfig(path)
+			if err != nil {
+				return Errorf(ErrClient, "%s", err)
+			}
+			req.Config = cfg
+		}
+	}
+
 	optDefs := map[Option]struct{}{}
 
 	for _, optDef := range optDefMap {
 		optDefs[optDef] = struct{}{}
 	}
 
-Outer:
 	for optDef := range optDefs {
-		dflt := optDef.Default()
-		if dflt == nil {
-			// option has no dflt, continue
-			continue
+		if err := req.resolveOption(optDef, local); err != nil {
+			return err
 		}
-
-		names := optDef.Names()
-		for _, name := range names {
-			if _, ok := req.Options[name]; ok {
-				// option has been set, continue with next option
-				continue Outer
-			}
-		}
-
-		req.Options[optDef.Name()] = dflt
 	}
 
 	return nil
@@ -78,10 +78,25 @@ type ResponseEmitter interface {
 func Copy(re ResponseEmitter, res Response) error {
 	re.SetLength(res.Length())
 
+	if pr, ok := res.(ProgressResponse); ok {
+		if pe, ok := re.(ProgressEmitter); ok {
+			pr.SetProgressHandler(func(p Progress) {
+				if err := pe.EmitProgress(p); err != nil {
+					log.Debugf("error forwarding progress: %s", err)
+				}
+			})
+		}
+	}
+
 	for {
 		v, err := res.Next()
 		if err != nil {
 			if err == io.EOF {
+				if cr, ok := res.(CursorResponse); ok && cr.NextCursor() != "" {
+					if err := SetNextCursor(re, cr.NextCursor()); err != nil {
+						return err
+					}
+				}
 				return re.Close()
 			}
 
@@ -0,0 +1,344 @@
+package cmds
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// SourceKind is the kind of place the value of an option came from.
+type SourceKind int
+
+const (
+	// SourceFlag values were set by the caller, e.g. on the command line or
+	// in the query string.
+	SourceFlag SourceKind = iota + 1
+	// SourceEnv values were read from an environment variable.
+	SourceEnv
+	// SourceConfig values were read from the config file.
+	SourceConfig
+	// SourceDefault values are the defaults of the options.
+	SourceDefault
+)
+
+// OptionSource records where the value of an option came from.
+type OptionSource struct {
+	Kind SourceKind
+	// Name is the environment variable or the path of the config file the
+	// value was read from, or the deprecated option it was set with, if
+	// any.
+	Name string
+}
+
+// EnvOption is implemented by options that can be set with environment
+// variables, like all options created by this package.
+type EnvOption interface {
+	Option
+
+	WithEnv(vars ...string) Option // reads unset values from the first set environment variable
+	Env() []string
+}
+
+// WithEnv makes local requests read unset values of opt from the first of
+// vars that is set. It panics if opt isn't an EnvOption.
+func WithEnv(opt Option, vars ...string) Option {
+	e, ok := opt.(EnvOption)
+	if !ok {
+		panic(fmt.Errorf("option %q can't be set with environment variables", opt.Name()))
+	}
+	return e.WithEnv(vars...)
+}
+
+// OptionEnv returns the environment variables opt is read from, if it is an
+// EnvOption.
+func OptionEnv(opt Option) []string {
+	if e, ok := opt.(EnvOption); ok {
+		return e.Env()
+	}
+	return nil
+}
+
+// String describes the source for help texts, e.g. "env IPFS_TIMEOUT".
+func (s OptionSource) String() string {
+	var kind string
+	switch s.Kind {
+	case SourceFlag:
+		kind = "flag"
+	case SourceEnv:
+		kind = "env"
+	case SourceConfig:
+		kind = "config"
+	case SourceDefault:
+		kind = "default"
+	default:
+		return "unknown"
+	}
+
+	switch {
+	case s.Name == "":
+		return kind
+	case s.Kind == SourceFlag:
+		return kind + " --" + s.Name
+	default:
+		return kind + " " + s.Name
+	}
+}
+
+// Config holds option values read from a config file. Top-level keys are
+// option names. Tables named after subcommands hold the options of that
+// subcommand, and take precedence over the top-level keys, e.g.
+//
+//	timeout = "1m"
+//
+//	[add]
+//	timeout = "10m"
+type Config struct {
+	// Path is the file the config was read from, if any.
+	Path   string
+	Values map[string]any
+}
+
+// LoadConfig reads a config file. The format is picked by the file
+// extension: .json, .toml, .yaml or .yml.
+func LoadConfig(path string) (*Config, error) {
+	var enc EncodingType
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		enc = JSON
+	case ".toml":
+		enc = TOML
+	case ".yaml", ".yml":
+		enc = YAML
+	default:
+		return nil, fmt.Errorf("unknown config file format %q", filepath.Ext(path))
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cfg, err := ParseConfig(f, enc)
+	if err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	cfg.Path = path
+	return cfg, nil
+}
+
+// ParseConfig reads a config in the given encoding, one of JSON, TOML and
+// YAML.
+func ParseConfig(r io.Reader, enc EncodingType) (*Config, error) {
+	values := map[string]any{}
+
+	var err error
+	switch enc {
+	case JSON:
+		err = json.NewDecoder(r).Decode(&values)
+	case TOML:
+		_, err = toml.NewDecoder(r).Decode(&values)
+	case YAML:
+		err = yaml.NewDecoder(r).Decode(&values)
+		if err == io.EOF {
+			// an empty document
+			err = nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config encoding %q", enc)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Config{Values: values}, nil
+}
+
+// lookup returns the value of opt for the command at path. The table of
+// the deepest command that sets the option wins.
+func (c *Config) lookup(path []string, opt Option) (any, bool) {
+	tables := []map[string]any{c.Values}
+	for _, name := range path {
+		t, ok := tables[len(tables)-1][name].(map[string]any)
+		if !ok {
+			break
+		}
+		tables = append(tables, t)
+	}
+
+	for i := len(tables) - 1; i >= 0; i-- {
+		for _, name := range opt.Names() {
+			if v, ok := tables[i][name]; ok {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// OptionSource returns where the value of the option with the given name
+// came from. It returns false if the option isn't set or was set after
+// FillDefaults.
+func (req *Request) OptionSource(name string) (OptionSource, bool) {
+	if src, ok := req.Sources[name]; ok {
+		return src, true
+	}
+
+	optDefs, err := req.Root.GetOptions(req.Path)
+	if err != nil {
+		return OptionSource{}, false
+	}
+	if opt, ok := optDefs[name]; ok {
+		for _, n := range opt.Names() {
+			if src, ok := req.Sources[n]; ok {
+				return src, true
+			}
+		}
+	}
+	return OptionSource{}, false
+}
+
+// resolveOption sets the value of opt from the first layer that has one:
+// the options set by the caller, including deprecated options replaced by
+// opt, the environment and the config file if local is set, and the
+// default.
+func (req *Request) resolveOption(opt Option, local bool) error {
+	for _, name := range opt.Names() {
+		if _, ok := req.Options[name]; ok {
+			if _, ok := req.Sources[name]; !ok {
+				req.Sources[name] = OptionSource{Kind: SourceFlag}
+			}
+			return nil
+		}
+	}
+
+	if v, name, ok := req.deprecatedValue(opt); ok {
+		req.setResolved(opt, v, OptionSource{Kind: SourceFlag, Name: name})
+		return nil
+	}
+
+	if local {
+		if ok, err := req.resolveLocal(opt); ok || err != nil {
+			return err
+		}
+	}
+
+	if dflt := OptionVersionDefault(opt, req.APIVersion); dflt != nil {
+		req.setResolved(opt, dflt, OptionSource{Kind: SourceDefault})
+	}
+	return nil
+}
+
+// resolveLocal sets the value of opt from the environment or the config
+// file, and reports whether it found one.
+func (req *Request) resolveLocal(opt Option) (bool, error) {
+	for _, env := range OptionEnv(opt) {
+		str, ok := os.LookupEnv(env)
+		if !ok {
+			continue
+		}
+		v, err := parseEnvValue(opt, str)
+		if err != nil {
+			return false, Errorf(ErrClient, "invalid value %q in environment variable %s for option %q: %s", str, env, opt.Name(), err)
+		}
+		req.setResolved(opt, v, OptionSource{Kind: SourceEnv, Name: env})
+		return true, nil
+	}
+
+	if req.Config != nil {
+		if raw, ok := req.Config.lookup(req.Path, opt); ok {
+			v, err := parseConfigValue(opt, raw)
+			if err != nil {
+				return false, Errorf(ErrClient, "invalid value %v in config file for option %q: %s", raw, opt.Name(), err)
+			}
+			req.setResolved(opt, v, OptionSource{Kind: SourceConfig, Name: req.Config.Path})
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (req *Request) setResolved(opt Option, v any, src OptionSource) {
+	req.Options[opt.Name()] = v
+	req.Sources[opt.Name()] = src
+}
+
+// parseEnvValue parses the value of an environment variable. Key-value
+// options take a comma-separated list of pairs.
+func parseEnvValue(opt Option, str string) (any, error) {
+	switch opt.Type() {
+	case Strings:
+		return parseStrings(opt, str)
+	case KeyValue:
+	default:
+		return opt.Parse(str)
+	}
+
+	res := map[string]string{}
+	for pair := range strings.SplitSeq(str, ",") {
+		v, err := opt.Parse(pair)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(res, v.(map[string]string))
+	}
+	return res, nil
+}
+
+// parseStrings parses a single value of a strings option, which is split
+// by delimited options, into a []string.
+func parseStrings(opt Option, str string) (any, error) {
+	v, err := opt.Parse(str)
+	if s, ok := v.(string); ok {
+		return []string{s}, err
+	}
+	return v, err
+}
+
+// parseConfigValue converts a value decoded from a config file to the type
+// of opt.
+func parseConfigValue(opt Option, v any) (any, error) {
+	switch opt.Type() {
+	case Strings:
+		vs, ok := v.([]any)
+		if !ok {
+			return parseStrings(opt, configString(v))
+		}
+		res := make([]string, len(vs))
+		for i, v := range vs {
+			res[i] = configString(v)
+		}
+		return res, nil
+	case KeyValue:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return parseEnvValue(opt, configString(v))
+		}
+		res := make(map[string]string, len(m))
+		for k, v := range m {
+			res[k] = configString(v)
+		}
+		return res, nil
+	default:
+		return opt.Parse(configString(v))
+	}
+}
+
+func configString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,236 @@
+package cmds
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sourcesRoot() *Command {
+	return &Command{
+		Options: []Option{
+			OptionConfigFile,
+			WithEnv(DurationOption("timeout", "t", "The timeout."), "TEST_TIMEOUT").WithDefault(time.Second),
+			WithEnv(StringOption("hash", "The hash function."), "TEST_HASH_OLD", "TEST_HASH").WithDefault("sha2-256"),
+			StringsOption("peer", "The peers."),
+			WithEnv(KeyValueOption("header", "The headers."), "TEST_HEADERS"),
+			IntOption("count", "The count."),
+		},
+		Subcommands: map[string]*Command{
+			"add": {},
+		},
+	}
+}
+
+func fillDefaults(t *testing.T, path []string, opts OptMap, cfg *Config) *Request {
+	t.Helper()
+	req, err := NewRequest(context.Background(), path, opts, nil, nil, sourcesRoot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Config = cfg
+	if err := req.FillLocalDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestFillDefaultsLayers(t *testing.T) {
+	cfg, err := ParseConfig(strings.NewReader(`{
+		"timeout": "1m",
+		"hash": "blake3",
+		"peer": ["a", "b"],
+		"count": 3,
+		"add": {"timeout": "10m"}
+	}`), JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Path = "test.json"
+
+	t.Setenv("TEST_HASH", "sha3-256")
+	t.Setenv("TEST_HEADERS", "a=1,b=2")
+
+	req := fillDefaults(t, nil, OptMap{"t": "5s"}, cfg)
+	optDefs, err := req.Root.GetOptions(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, exp := range map[string]struct {
+		v   any
+		src OptionSource
+	}{
+		"timeout": {5 * time.Second, OptionSource{Kind: SourceFlag}},
+		"hash":    {"sha3-256", OptionSource{Kind: SourceEnv, Name: "TEST_HASH"}},
+		"header":  {map[string]string{"a": "1", "b": "2"}, OptionSource{Kind: SourceEnv, Name: "TEST_HEADERS"}},
+		"peer":    {[]string{"a", "b"}, OptionSource{Kind: SourceConfig, Name: "test.json"}},
+		"count":   {3, OptionSource{Kind: SourceConfig, Name: "test.json"}},
+	} {
+		v, _ := optionValue(optDefs[name], req.Options)
+		if !reflect.DeepEqual(v, exp.v) {
+			t.Errorf("%s: expected %#v, got %#v", name, exp.v, v)
+		}
+		if src, ok := req.OptionSource(name); !ok || src != exp.src {
+			t.Errorf("%s: expected source %s, got %s", name, exp.src, src)
+		}
+	}
+
+	// subcommand tables override top-level keys
+	req = fillDefaults(t, []string{"add"}, OptMap{}, cfg)
+	if v := req.Options["timeout"]; v != 10*time.Minute {
+		t.Errorf("expected the timeout of the add table, got %v", v)
+	}
+
+	// the environment beats the config file, and the default comes last
+	t.Setenv("TEST_TIMEOUT", "2m")
+	req = fillDefaults(t, []string{"add"}, OptMap{}, nil)
+	if v := req.Options["timeout"]; v != 2*time.Minute {
+		t.Errorf("expected the timeout of the environment, got %v", v)
+	}
+	if src, _ := req.OptionSource("hash"); src.String() != "env TEST_HASH" {
+		t.Errorf("unexpected source %s", src)
+	}
+	os.Unsetenv("TEST_HASH")
+	req = fillDefaults(t, nil, OptMap{}, nil)
+	if src, _ := req.OptionSource("hash"); req.Options["hash"] != "sha2-256" || src.String() != "default" {
+		t.Errorf("expected the default, got %v from %s", req.Options["hash"], src)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"config.json": `{"count": 3, "peer": ["a"], "header": {"a": "1"}, "add": {"count": 4}}`,
+		"config.toml": "count = 3\npeer = [\"a\"]\nheader = {a = \"1\"}\n\n[add]\ncount = 4\n",
+		"config.yaml": "count: 3\npeer: [a]\nheader:\n  a: \"1\"\nadd:\n  count: 4\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		req := fillDefaults(t, []string{"add"}, OptMap{ConfigFileOpt: path}, nil)
+		if req.Config == nil || req.Config.Path != path {
+			t.Fatalf("%s: config file not loaded", name)
+		}
+		exp := OptMap{
+			ConfigFileOpt: path,
+			"timeout":     time.Second,
+			"hash":        "sha2-256",
+			"count":       4,
+			"peer":        []string{"a"},
+			"header":      map[string]string{"a": "1"},
+		}
+		if !reflect.DeepEqual(req.Options, exp) {
+			t.Errorf("%s: expected %v, got %v", name, exp, req.Options)
+		}
+	}
+
+	if _, err := LoadConfig(filepath.Join(dir, "config.ini")); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestFillDefaultsErrors(t *testing.T) {
+	t.Setenv("TEST_TIMEOUT", "soon")
+	req, err := NewRequest(context.Background(), nil, OptMap{}, nil, nil, sourcesRoot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = req.FillLocalDefaults()
+	if e, ok := err.(Error); !ok || e.Code != ErrClient || !strings.Contains(e.Message, "TEST_TIMEOUT") {
+		t.Errorf("expected a client error naming the variable, got %v", err)
+	}
+
+	os.Unsetenv("TEST_TIMEOUT")
+	cfg := &Config{Values: map[string]any{"count": "many"}}
+	req = &Request{Root: sourcesRoot(), Options: OptMap{}, Config: cfg}
+	err = req.FillLocalDefaults()
+	if e, ok := err.(Error); !ok || e.Code != ErrClient {
+		t.Errorf("expected a client error, got %v", err)
+	}
+}
+
+func TestFillDefaultsIgnoresLocalSources(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"hash": "blake3"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_TIMEOUT", "2m")
+
+	req, err := NewRequest(context.Background(), nil, OptMap{ConfigFileOpt: path}, nil, nil, sourcesRoot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := req.FillDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	if req.Config != nil {
+		t.Error("expected the config file not to be loaded")
+	}
+	if req.Options["timeout"] != time.Second || req.Options["hash"] != "sha2-256" {
+		t.Errorf("expected the defaults, got %v", req.Options)
+	}
+}
+
+func TestFillLocalDefaultsPlainOption(t *testing.T) {
+	t.Setenv("TEST_COUNT", "3")
+	opt := plainOption{IntOption("count", "The count.")}
+	if env := OptionEnv(opt); env != nil {
+		t.Fatalf("expected no environment variables, got %v", env)
+	}
+
+	req, err := NewRequest(context.Background(), nil, nil, nil, nil, &Command{Options: []Option{opt}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := req.FillLocalDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := req.Options["count"]; ok {
+		t.Errorf("expected count to be unset, got %v", req.Options)
+	}
+}
+
+func TestFillDefaultsStrings(t *testing.T) {
+	root := &Command{
+		Options: []Option{
+			WithEnv(StringsOption("peer", "The peers."), "TEST_PEERS"),
+			WithEnv(DelimitedStringsOption(",", "tag", "The tags."), "TEST_TAGS"),
+			NewOption(Strings, "name", "The names."),
+			DelimitedStringsOption(",", "label", "The labels."),
+		},
+	}
+	cfg, err := ParseConfig(strings.NewReader(`{"name": "bob", "label": "a,b"}`), JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_PEERS", "a,b")
+	t.Setenv("TEST_TAGS", "x,y")
+
+	req, err := NewRequest(context.Background(), nil, OptMap{}, nil, nil, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Config = cfg
+	if err := req.FillLocalDefaults(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, exp := range map[string][]string{
+		"peer":  {"a,b"},
+		"tag":   {"x", "y"},
+		"name":  {"bob"},
+		"label": {"a", "b"},
+	} {
+		v, ok := req.Options[name].([]string)
+		if !ok || !reflect.DeepEqual(v, exp) {
+			t.Errorf("%s: expected %q, got %#v", name, exp, req.Options[name])
+		}
+	}
+}
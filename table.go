@@ -0,0 +1,340 @@
+package cmds
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+// valueColumn is the name of the only column of tables of non-struct values.
+const valueColumn = "Value"
+
+type column struct {
+	name  string
+	index []int  // nil for the valueColumn and map keys
+	key   string // set for columns of map rows
+}
+
+// tabular turns command output into rows of cells. Columns are derived from
+// the fields of the command's Type (or, if it is not set, of the first
+// value), named like encoding/json would name them. Rows that are maps, like
+// the results of filters, have a column per key of the first row. A value
+// that is a slice produces one row per element.
+type tabular struct {
+	typ      reflect.Type
+	selected []string
+
+	columns []column
+	init    bool
+}
+
+func newTabular(req *Request) *tabular {
+	t := &tabular{}
+	if req == nil {
+		return t
+	}
+
+	// filtered values don't have the command's Type
+	if req.Command != nil && filterExpr(req) == "" {
+		t.typ = rowType(reflect.TypeOf(req.Command.Type))
+	}
+	// split here as well, as delimited options are only split by the CLI
+	selected, _ := req.Options[ColumnsOpt].([]string)
+	for _, s := range selected {
+		t.selected = append(t.selected, strings.Split(s, ",")...)
+	}
+	return t
+}
+
+// rowType returns the struct type of a single row, or nil if rows of t are
+// scalar values.
+func rowType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() != reflect.Uint8 {
+		t = t.Elem()
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+	}
+
+	if t == nil || t.Kind() != reflect.Struct || t == cidType ||
+		t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) ||
+		t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return nil
+	}
+	return t
+}
+
+func structColumns(t reflect.Type) []column {
+	var columns []column
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || (f.Anonymous && f.Type.Kind() == reflect.Struct) {
+			continue
+		}
+
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = f.Name
+		}
+
+		columns = append(columns, column{name: name, index: f.Index})
+	}
+	return columns
+}
+
+func mapColumns(rv reflect.Value) []column {
+	rv = indirect(rv)
+	keys := make([]string, 0, rv.Len())
+	for _, k := range rv.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	slices.Sort(keys)
+
+	columns := make([]column, len(keys))
+	for i, k := range keys {
+		columns[i] = column{name: k, key: k}
+	}
+	return columns
+}
+
+func isStringMap(rv reflect.Value) bool {
+	rv = indirect(rv)
+	return rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String
+}
+
+// indirect follows pointers and interfaces, up to the first nil.
+func indirect(rv reflect.Value) reflect.Value {
+	for rv.IsValid() && (rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface) && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	return rv
+}
+
+func (t *tabular) setup(rows []reflect.Value) error {
+	t.init = true
+
+	if t.typ == nil && len(rows) > 0 && rows[0].IsValid() {
+		t.typ = rowType(rows[0].Type())
+	}
+
+	var columns []column
+	switch {
+	case t.typ != nil:
+		columns = structColumns(t.typ)
+	case len(rows) > 0 && isStringMap(rows[0]):
+		columns = mapColumns(rows[0])
+	default:
+		columns = []column{{name: valueColumn}}
+	}
+
+	if len(t.selected) == 0 {
+		t.columns = columns
+		return nil
+	}
+
+	for _, name := range t.selected {
+		name = strings.TrimSpace(name)
+		i := -1
+		for j, c := range columns {
+			if strings.EqualFold(c.name, name) {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			return Errorf(ErrClient, "unknown column %q", name)
+		}
+		t.columns = append(t.columns, columns[i])
+	}
+	return nil
+}
+
+func (t *tabular) header() []string {
+	names := make([]string, len(t.columns))
+	for i, c := range t.columns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// rows returns the rows for v. The first call also sets up the columns and
+// returns them as the first row.
+func (t *tabular) rows(v any) ([][]string, error) {
+	var values []reflect.Value
+
+	rv := indirect(reflect.ValueOf(v))
+	if (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Type().Elem().Kind() != reflect.Uint8 {
+		for i := 0; i < rv.Len(); i++ {
+			values = append(values, rv.Index(i))
+		}
+	} else {
+		values = append(values, rv)
+	}
+
+	var rows [][]string
+	if !t.init {
+		if err := t.setup(values); err != nil {
+			return nil, err
+		}
+		rows = append(rows, t.header())
+	}
+
+	for _, rv := range values {
+		row, err := t.row(rv)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (t *tabular) row(rv reflect.Value) ([]string, error) {
+	if t.typ != nil {
+		rv = indirect(rv)
+		if !rv.IsValid() {
+			return nil, fmt.Errorf("unexpected nil value, expected %v", t.typ)
+		}
+		if rv.Type() != t.typ {
+			return nil, fmt.Errorf("unexpected type %v, expected %v", rv.Type(), t.typ)
+		}
+	}
+
+	cells := make([]string, len(t.columns))
+	for i, c := range t.columns {
+		fv := rv
+		switch {
+		case c.key != "":
+			m := indirect(rv)
+			if !m.IsValid() {
+				continue
+			}
+			if m.Kind() != reflect.Map {
+				return nil, fmt.Errorf("unexpected type %v, expected a map", m.Type())
+			}
+			fv = m.MapIndex(reflect.ValueOf(c.key).Convert(m.Type().Key()))
+		case c.index != nil:
+			var err error
+			fv, err = rv.FieldByIndexErr(c.index)
+			if err != nil {
+				// field of a nil embedded struct
+				continue
+			}
+		}
+
+		var err error
+		if cells[i], err = cell(fv); err != nil {
+			return nil, err
+		}
+	}
+	return cells, nil
+}
+
+func cell(rv reflect.Value) (string, error) {
+	tree, err := treeOf(rv)
+	if err != nil {
+		return "", err
+	}
+
+	switch v := tree.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v), nil
+	case cid.Cid:
+		return v.String(), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case bool, int64, uint64:
+		return fmt.Sprint(v), nil
+	default:
+		data, err := json.Marshal(jsonCompatTree(v))
+		return string(data), err
+	}
+}
+
+// TableEncoder writes values as rows of a table with aligned columns,
+// preceded by a header. As values are written as they are emitted, columns
+// are aligned within the rows of a single value, and only widen for the
+// rows following a wider cell.
+type TableEncoder struct {
+	w      io.Writer
+	t      *tabular
+	widths []int
+}
+
+// NewTableEncoder returns an encoder writing a table to w. Columns are
+// derived from the request's command Type and can be selected with the
+// ColumnsOpt option.
+func NewTableEncoder(req *Request, w io.Writer) *TableEncoder {
+	return &TableEncoder{w: w, t: newTabular(req)}
+}
+
+func (e *TableEncoder) Encode(v any) error {
+	rows, err := e.t.rows(v)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if e.widths == nil {
+			e.widths = make([]int, len(row))
+		}
+		for i, c := range row {
+			e.widths[i] = max(e.widths[i], utf8.RuneCountInString(c))
+		}
+	}
+
+	var b strings.Builder
+	for _, row := range rows {
+		var line strings.Builder
+		for i, c := range row {
+			line.WriteString(c)
+			line.WriteString(strings.Repeat(" ", e.widths[i]-utf8.RuneCountInString(c)+2))
+		}
+		b.WriteString(strings.TrimRight(line.String(), " "))
+		b.WriteString("\n")
+	}
+
+	_, err = io.WriteString(e.w, b.String())
+	return err
+}
+
+// CSVEncoder writes values as CSV records, preceded by a header record.
+type CSVEncoder struct {
+	w *csv.Writer
+	t *tabular
+}
+
+// NewCSVEncoder returns an encoder writing CSV to w. Columns are derived
+// from the request's command Type and can be selected with the ColumnsOpt
+// option.
+func NewCSVEncoder(req *Request, w io.Writer) *CSVEncoder {
+	return &CSVEncoder{w: csv.NewWriter(w), t: newTabular(req)}
+}
+
+func (e *CSVEncoder) Encode(v any) error {
+	rows, err := e.t.rows(v)
+	if err != nil {
+		return err
+	}
+
+	// WriteAll flushes, so records reach the client as they are emitted.
+	return e.w.WriteAll(rows)
+}
@@ -0,0 +1,91 @@
+package cmds
+
+import (
+	"bytes"
+	"testing"
+)
+
+type tableTestObj struct {
+	Name   string
+	Size   uint64 `json:"size"`
+	Hidden string `json:"-"`
+	Tags   []string
+}
+
+func TestTableEncoder(t *testing.T) {
+	req := &Request{Command: &Command{Type: &tableTestObj{}}}
+
+	buf := new(bytes.Buffer)
+	enc := NewTableEncoder(req, buf)
+	for _, v := range []any{
+		&tableTestObj{Name: "foo", Size: 1234, Hidden: "x"},
+		[]tableTestObj{{Name: "a", Size: 1, Tags: []string{"t"}}, {Name: "longer name", Size: 2}},
+	} {
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exp := `Name  size  Tags
+foo   1234
+a            1     ["t"]
+longer name  2
+`
+	if buf.String() != exp {
+		t.Fatalf("expected:\n%q\ngot:\n%q", exp, buf.String())
+	}
+
+	if err := enc.Encode(Foo{}); err == nil {
+		t.Fatal("expected error encoding a value of the wrong type")
+	}
+}
+
+func TestTableEncoderScalar(t *testing.T) {
+	buf := new(bytes.Buffer)
+	enc := NewTableEncoder(&Request{Command: &Command{}}, buf)
+	for _, v := range []any{"foo", 42} {
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exp := "Value\nfoo\n42\n"
+	if buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+}
+
+func TestCSVEncoderColumns(t *testing.T) {
+	req := &Request{
+		Command: &Command{Type: tableTestObj{}},
+		Options: OptMap{ColumnsOpt: []string{"SIZE,tags", "name"}},
+	}
+
+	buf := new(bytes.Buffer)
+	enc := NewCSVEncoder(req, buf)
+	for _, v := range []any{
+		tableTestObj{Name: "foo, bar", Size: 1},
+		tableTestObj{Name: "baz", Size: 2, Tags: []string{"a", "b"}},
+	} {
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exp := "size,Tags,Name\n1,,\"foo, bar\"\n2,\"[\"\"a\"\",\"\"b\"\"]\",baz\n"
+	if buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+}
+
+func TestTableUnknownColumn(t *testing.T) {
+	req := &Request{
+		Command: &Command{Type: tableTestObj{}},
+		Options: OptMap{ColumnsOpt: []string{"nope"}},
+	}
+
+	err := NewCSVEncoder(req, new(bytes.Buffer)).Encode(tableTestObj{})
+	if e, ok := err.(Error); !ok || e.Code != ErrClient {
+		t.Fatalf("expected client error, got %v", err)
+	}
+}
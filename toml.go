@@ -0,0 +1,121 @@
+package cmds
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/BurntSushi/toml"
+)
+
+// tomlSeparator ends every TOML document of a stream. TOML has no notion of
+// multiple documents, so we borrow the delimiter used for TOML front matter.
+const tomlSeparator = "+++"
+
+// TOMLEncoder encodes every value as a separate TOML document. As the top
+// level of a TOML document is a table, only values that encode to a map or
+// struct are supported. Every document ends with a "+++" line, so that
+// readers of a stream know it is complete without waiting for the next one.
+// Null values are omitted because TOML can't represent them.
+type TOMLEncoder struct {
+	w io.Writer
+}
+
+// NewTOMLEncoder returns an encoder writing TOML documents to w.
+func NewTOMLEncoder(w io.Writer) *TOMLEncoder {
+	return &TOMLEncoder{w: w}
+}
+
+func (e *TOMLEncoder) Encode(v any) error {
+	tree, err := valueTree(v)
+	if err != nil {
+		return err
+	}
+
+	table, ok := tomlTree(jsonCompatTree(tree)).(map[string]any)
+	if !ok {
+		return fmt.Errorf("cannot encode %T as toml: value is not a table", v)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := toml.NewEncoder(buf).Encode(table); err != nil {
+		return err
+	}
+	buf.WriteString(tomlSeparator + "\n")
+
+	_, err = e.w.Write(buf.Bytes())
+	return err
+}
+
+// tomlTree drops null values, which TOML can't represent.
+func tomlTree(v any) any {
+	switch v := v.(type) {
+	case []any:
+		l := make([]any, 0, len(v))
+		for _, e := range v {
+			if e != nil {
+				l = append(l, tomlTree(e))
+			}
+		}
+		return l
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			if e != nil {
+				m[k] = tomlTree(e)
+			}
+		}
+		return m
+	default:
+		return v
+	}
+}
+
+// TOMLDecoder decodes values from a stream of TOML documents ended by "+++"
+// lines. The separator of the last document is optional.
+type TOMLDecoder struct {
+	r *bufio.Reader
+}
+
+// NewTOMLDecoder returns a decoder reading TOML documents from r.
+func NewTOMLDecoder(r io.Reader) *TOMLDecoder {
+	return &TOMLDecoder{r: bufio.NewReader(r)}
+}
+
+func (d *TOMLDecoder) Decode(v any) error {
+	doc, err := d.next()
+	if err != nil {
+		return err
+	}
+
+	var table map[string]any
+	if err := toml.Unmarshal(doc, &table); err != nil {
+		return err
+	}
+	return unmarshalTree(table, v)
+}
+
+// next returns the next document of the stream, as soon as its separator
+// was read.
+func (d *TOMLDecoder) next() ([]byte, error) {
+	var doc []byte
+	for {
+		line, err := d.r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		if string(bytes.TrimSpace(line)) == tomlSeparator {
+			return doc, nil
+		}
+		doc = append(doc, line...)
+
+		if err == io.EOF {
+			if len(bytes.TrimSpace(doc)) == 0 {
+				return nil, io.EOF
+			}
+			return doc, nil
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package cmds
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTOMLStream(t *testing.T) {
+	c := mustCid(t, "bafkqaaa")
+
+	buf := new(bytes.Buffer)
+	enc := NewTOMLEncoder(buf)
+	for _, v := range []any{
+		&linkTestObj{Name: "a", Link: c, Size: 1},
+		linkTestObj{Name: "b", Data: []byte("hi"), Size: 2},
+	} {
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := enc.Encode("not a table"); err == nil {
+		t.Fatal("expected error encoding a string")
+	}
+
+	exp := `Name = "a"
+size = 1
+
+[Link]
+  "/" = "bafkqaaa"
++++
+Data = "aGk="
+Name = "b"
+size = 2
++++
+`
+	if buf.String() != exp {
+		t.Fatalf("expected:\n%s\ngot:\n%s", exp, buf.String())
+	}
+
+	dec := NewTOMLDecoder(buf)
+	for _, exp := range []*linkTestObj{
+		{Name: "a", Link: c, Size: 1},
+		{Name: "b", Data: []byte("hi"), Size: 2},
+	} {
+		m := &MaybeError{Value: &linkTestObj{}}
+		if err := dec.Decode(m); err != nil {
+			t.Fatal(err)
+		}
+		v, err := m.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(v, exp) {
+			t.Fatalf("expected %#v, got %#v", exp, v)
+		}
+	}
+
+	if err := dec.Decode(&MaybeError{}); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func TestTOMLDecoderStreams(t *testing.T) {
+	r, w := io.Pipe()
+	defer r.Close()
+
+	next := make(chan struct{})
+	go func() {
+		enc := NewTOMLEncoder(w)
+		enc.Encode(Foo{1})
+		// the next document is only sent after the first was decoded
+		<-next
+		enc.Encode(Foo{2})
+		w.Close()
+	}()
+
+	dec := NewTOMLDecoder(r)
+	decoded := make(chan Foo, 1)
+	go func() {
+		var v Foo
+		if err := dec.Decode(&v); err != nil {
+			t.Error(err)
+		}
+		decoded <- v
+	}()
+
+	select {
+	case v := <-decoded:
+		if v.Bar != 1 {
+			t.Fatalf("expected 1, got %d", v.Bar)
+		}
+	case <-time.After(5 * time.Second):
+		close(next)
+		t.Fatal("the document wasn't decoded before the next one was sent")
+	}
+	close(next)
+
+	var v Foo
+	if err := dec.Decode(&v); err != nil || v.Bar != 2 {
+		t.Fatalf("expected 2, got %d, %v", v.Bar, err)
+	}
+	if err := dec.Decode(&v); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
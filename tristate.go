@@ -0,0 +1,45 @@
+package cmds
+
+// TriBool is the value of a Tristate option. Its zero value is Unset, so
+// commands can read the option without checking whether it was set:
+//
+//	quiet, _ := req.Options["quiet"].(cmds.TriBool)
+//	if quiet == cmds.Unset { ... }
+type TriBool int8
+
+const (
+	// Unset options were neither enabled nor disabled.
+	Unset TriBool = iota
+	// True options were enabled, e.g. with --flag.
+	True
+	// False options were disabled, e.g. with --no-flag.
+	False
+)
+
+// IsSet reports whether t is True or False.
+func (t TriBool) IsSet() bool {
+	return t != Unset
+}
+
+// Bool returns whether t is True, or def if t is Unset.
+func (t TriBool) Bool(def bool) bool {
+	switch t {
+	case True:
+		return true
+	case False:
+		return false
+	default:
+		return def
+	}
+}
+
+func (t TriBool) String() string {
+	switch t {
+	case True:
+		return "true"
+	case False:
+		return "false"
+	default:
+		return "unset"
+	}
+}
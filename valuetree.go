@@ -0,0 +1,296 @@
+package cmds
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+// Encodings other than JSON go through an intermediate tree of generic
+// values. valueTree builds that tree from a command's output following the
+// rules of encoding/json (field tags, json.Marshaler, ...), so that all
+// encodings agree on field names. The tree consists of nil, bool, int64,
+// uint64, float64, string, []byte, cid.Cid, []any and map[string]any values,
+// which keeps byte strings and CID links intact for encodings that can
+// represent them natively.
+//
+// unmarshalTree goes the other way: it turns such a tree back into JSON and
+// unmarshals it into the target value, so decoders support everything
+// json.Unmarshal does, including MaybeError.
+
+var (
+	cidType           = reflect.TypeFor[cid.Cid]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+func valueTree(v any) (any, error) {
+	return treeOf(reflect.ValueOf(v))
+}
+
+func treeOf(rv reflect.Value) (any, error) {
+	if !rv.IsValid() {
+		return nil, nil
+	}
+
+	if rv.Type() == cidType {
+		c := rv.Interface().(cid.Cid)
+		if !c.Defined() {
+			return nil, nil
+		}
+		return c, nil
+	}
+
+	if (rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface) && rv.IsNil() {
+		return nil, nil
+	}
+	// *cid.Cid implements json.Marshaler too, but must stay a link
+	if rv.Kind() == reflect.Pointer && rv.Type().Elem() == cidType {
+		return treeOf(rv.Elem())
+	}
+
+	if m, ok := marshaler(rv, jsonMarshalerType); ok {
+		data, err := m.(json.Marshaler).MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		return jsonTree(data)
+	}
+	if m, ok := marshaler(rv, textMarshalerType); ok {
+		text, err := m.(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return string(text), nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return treeOf(rv.Elem())
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil, nil
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return b, nil
+		}
+
+		l := make([]any, rv.Len())
+		for i := range l {
+			var err error
+			if l[i], err = treeOf(rv.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return l, nil
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil, nil
+		}
+
+		m := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			k, err := mapKey(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			if m[k], err = treeOf(iter.Value()); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case reflect.Struct:
+		m := make(map[string]any)
+		return m, structTree(rv, m)
+	default:
+		return nil, fmt.Errorf("unsupported type %s", rv.Type())
+	}
+}
+
+// marshaler returns rv (or its address) as an interface value if it
+// implements iface, just like encoding/json would pick it up.
+func marshaler(rv reflect.Value, iface reflect.Type) (any, bool) {
+	if rv.Kind() != reflect.Pointer && rv.CanAddr() && reflect.PointerTo(rv.Type()).Implements(iface) {
+		return rv.Addr().Interface(), true
+	}
+	if rv.Type().Implements(iface) {
+		return rv.Interface(), true
+	}
+	return nil, false
+}
+
+func mapKey(k reflect.Value) (string, error) {
+	if m, ok := marshaler(k, textMarshalerType); ok {
+		text, err := m.(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+
+	switch k.Kind() {
+	case reflect.String:
+		return k.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	default:
+		return "", fmt.Errorf("unsupported map key type %s", k.Type())
+	}
+}
+
+func structTree(rv reflect.Value, m map[string]any) error {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fv := rv.Field(i)
+
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		// promote the fields of untagged embedded structs, without
+		// overwriting fields of the embedding struct
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					continue
+				}
+				ft, fv = ft.Elem(), fv.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded := make(map[string]any)
+				if err := structTree(fv, embedded); err != nil {
+					return err
+				}
+				for k, v := range embedded {
+					if _, ok := m[k]; !ok {
+						m[k] = v
+					}
+				}
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if strings.Contains(","+opts+",", ",omitempty,") && isEmptyValue(fv) {
+			continue
+		}
+
+		v, err := treeOf(fv)
+		if err != nil {
+			return err
+		}
+		m[name] = v
+	}
+
+	return nil
+}
+
+// isEmptyValue reports whether encoding/json's omitempty would drop v.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// jsonTree parses JSON data into a value tree, preserving integers.
+func jsonTree(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return numbersTree(v), nil
+}
+
+func numbersTree(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return u
+		}
+		f, _ := v.Float64()
+		return f
+	case []any:
+		for i := range v {
+			v[i] = numbersTree(v[i])
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = numbersTree(v[k])
+		}
+	}
+	return v
+}
+
+func unmarshalTree(tree any, v any) error {
+	data, err := json.Marshal(jsonCompatTree(tree))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// jsonCompatTree replaces byte strings and CIDs with the values
+// encoding/json expects for []byte and cid.Cid.
+func jsonCompatTree(v any) any {
+	switch v := v.(type) {
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case cid.Cid:
+		return map[string]any{"/": v.String()}
+	case []any:
+		l := make([]any, len(v))
+		for i := range v {
+			l[i] = jsonCompatTree(v[i])
+		}
+		return l
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k := range v {
+			m[k] = jsonCompatTree(v[k])
+		}
+		return m
+	default:
+		return v
+	}
+}
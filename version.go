@@ -0,0 +1,70 @@
+package cmds
+
+import "fmt"
+
+// VersionRange limits a command to a range of API versions. The zero value
+// serves the command in every version.
+type VersionRange struct {
+	// Since is the first version serving the command.
+	Since int
+	// Until is the first version that no longer serves the command, or 0
+	// if the command hasn't been removed.
+	Until int
+}
+
+// Contains reports whether version serves the command.
+func (v VersionRange) Contains(version int) bool {
+	return version >= v.Since && (v.Until == 0 || version < v.Until)
+}
+
+// String describes the range, e.g. "v1 until v3".
+func (v VersionRange) String() string {
+	if v.Until == 0 {
+		return fmt.Sprintf("since v%d", v.Since)
+	}
+	return fmt.Sprintf("v%d until v%d", v.Since, v.Until)
+}
+
+// InVersion reports whether the command at path is served in the given API
+// version, i.e. whether the version ranges of all commands along path
+// contain it.
+func (c *Command) InVersion(path []string, version int) bool {
+	cmds, err := c.Resolve(path)
+	if err != nil {
+		return false
+	}
+	for _, cmd := range cmds {
+		if !cmd.Versions.Contains(version) {
+			return false
+		}
+	}
+	return true
+}
+
+// VersionedOption is implemented by options whose default can change with
+// the API version, like all options created by this package.
+type VersionedOption interface {
+	Option
+
+	WithVersionDefault(version int, v any) Option // changes the default from the given API version on
+	VersionDefault(version int) any
+}
+
+// WithVersionDefault sets the default of opt for requests to the given API
+// version and later ones. It panics if opt isn't a VersionedOption.
+func WithVersionDefault(opt Option, version int, v any) Option {
+	vo, ok := opt.(VersionedOption)
+	if !ok {
+		panic(fmt.Errorf("option %q can't have version defaults", opt.Name()))
+	}
+	return vo.WithVersionDefault(version, v)
+}
+
+// OptionVersionDefault returns the default of opt for requests to the given
+// API version, which is Default unless opt is a VersionedOption.
+func OptionVersionDefault(opt Option, version int) any {
+	if vo, ok := opt.(VersionedOption); ok {
+		return vo.VersionDefault(version)
+	}
+	return opt.Default()
+}
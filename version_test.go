@@ -0,0 +1,43 @@
+package cmds
+
+import "testing"
+
+func TestVersionRange(t *testing.T) {
+	root := &Command{
+		Subcommands: map[string]*Command{
+			"old": {
+				Versions: VersionRange{Until: 1},
+				Subcommands: map[string]*Command{
+					"sub": {Versions: VersionRange{Since: 2}},
+				},
+			},
+			"new": {Versions: VersionRange{Since: 1, Until: 3}},
+		},
+	}
+
+	tcs := []struct {
+		path    []string
+		version int
+		exp     bool
+	}{
+		{nil, 7, true},
+		{[]string{"old"}, 0, true},
+		{[]string{"old"}, 1, false},
+		{[]string{"old", "sub"}, 0, false},
+		{[]string{"old", "sub"}, 2, false},
+		{[]string{"new"}, 0, false},
+		{[]string{"new"}, 2, true},
+		{[]string{"new"}, 3, false},
+		{[]string{"nope"}, 0, false},
+	}
+	for _, tc := range tcs {
+		if got := root.InVersion(tc.path, tc.version); got != tc.exp {
+			t.Errorf("%v in version %d: expected %v, got %v", tc.path, tc.version, tc.exp, got)
+		}
+	}
+
+	root.Subcommands["bad"] = &Command{Versions: VersionRange{Since: 2, Until: 2}}
+	if errs := root.DebugValidate(); len(errs["/bad"]) != 1 {
+		t.Errorf("expected an empty range error, got %v", errs)
+	}
+}
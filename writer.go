@@ -189,6 +189,9 @@ type MaybeError struct {
 	Value any // needs to be a pointer
 	Error *Error
 
+	// Progress is set if a ProgressFrame was decoded instead of a value.
+	Progress *Progress
+
 	isError bool
 }
 
@@ -200,6 +203,15 @@ func (m *MaybeError) Get() (any, error) {
 }
 
 func (m *MaybeError) UnmarshalJSON(data []byte) error {
+	if isProgressFrame(data) {
+		var f ProgressFrame
+		if err := json.Unmarshal(data, &f); err != nil {
+			return err
+		}
+		m.Progress = &f.Progress
+		return nil
+	}
+
 	var e Error
 	err := json.Unmarshal(data, &e)
 	if err != nil {
@@ -0,0 +1,52 @@
+package cmds
+
+import (
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLEncoder encodes every value as a separate YAML document. Each
+// document starts with a "---" marker, so a stream of values can be read
+// with any multi-document YAML parser.
+type YAMLEncoder struct {
+	w io.Writer
+}
+
+// NewYAMLEncoder returns an encoder writing YAML documents to w.
+func NewYAMLEncoder(w io.Writer) *YAMLEncoder {
+	return &YAMLEncoder{w: w}
+}
+
+func (e *YAMLEncoder) Encode(v any) error {
+	tree, err := valueTree(v)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(jsonCompatTree(tree))
+	if err != nil {
+		return err
+	}
+
+	_, err = e.w.Write(append([]byte("---\n"), data...))
+	return err
+}
+
+// YAMLDecoder decodes values from a stream of YAML documents.
+type YAMLDecoder struct {
+	dec *yaml.Decoder
+}
+
+// NewYAMLDecoder returns a decoder reading YAML documents from r.
+func NewYAMLDecoder(r io.Reader) *YAMLDecoder {
+	return &YAMLDecoder{dec: yaml.NewDecoder(r)}
+}
+
+func (d *YAMLDecoder) Decode(v any) error {
+	var doc any
+	if err := d.dec.Decode(&doc); err != nil {
+		return err
+	}
+	return unmarshalTree(doc, v)
+}
@@ -0,0 +1,74 @@
+package cmds
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestYAMLStream(t *testing.T) {
+	c := mustCid(t, "bafkqaaa")
+
+	buf := new(bytes.Buffer)
+	enc := NewYAMLEncoder(buf)
+	for _, v := range []any{
+		&linkTestObj{Name: "a", Link: c, Size: 1},
+		linkTestObj{Name: "b", Data: []byte("hi"), Size: 2},
+		Error{Message: "some error"},
+	} {
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exp := `---
+Data: null
+Link:
+    /: bafkqaaa
+Name: a
+size: 1
+---
+Data: aGk=
+Link: null
+Name: b
+size: 2
+---
+Code: 0
+Message: some error
+Type: error
+`
+	if buf.String() != exp {
+		t.Fatalf("expected:\n%s\ngot:\n%s", exp, buf.String())
+	}
+
+	dec := NewYAMLDecoder(buf)
+	for _, exp := range []*linkTestObj{
+		{Name: "a", Link: c, Size: 1},
+		{Name: "b", Data: []byte("hi"), Size: 2},
+	} {
+		m := &MaybeError{Value: &linkTestObj{}}
+		if err := dec.Decode(m); err != nil {
+			t.Fatal(err)
+		}
+		v, err := m.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(v, exp) {
+			t.Fatalf("expected %#v, got %#v", exp, v)
+		}
+	}
+
+	m := &MaybeError{Value: &linkTestObj{}}
+	if err := dec.Decode(m); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Get(); err == nil || err.Error() != "some error" {
+		t.Fatalf("expected error %q, got %v", "some error", err)
+	}
+
+	if err := dec.Decode(&MaybeError{}); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}